@@ -0,0 +1,77 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+import (
+	"context"
+	"sort"
+
+	bw "github.com/blamewarrior/hooks/blamewarrior"
+	gh "github.com/blamewarrior/hooks/github"
+	"github.com/blamewarrior/hooks/logging"
+)
+
+// BlamePicker ranks candidates by the number of lines touched by pull request they
+// have authored. Candidates who authored none of them are picked by fallback picker,
+// as are all candidates if pull request cannot be blamed.
+type BlamePicker struct {
+	blame    gh.Blame
+	fallback ReviewerPicker
+}
+
+func NewBlamePicker(blame gh.Blame, fallback ReviewerPicker) *BlamePicker {
+	return &BlamePicker{blame, fallback}
+}
+
+func (picker *BlamePicker) Pick(ctx context.Context, pullRequest *bw.PullRequest, candidates []gh.Collaborator, n int) ([]gh.Collaborator, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNoEligibleReviewer
+	}
+
+	authorship, err := picker.blame.Authorship(gh.Context{Context: ctx}, pullRequest.RepositoryName, pullRequest.Number)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Warnf("failed to blame pull request, picking reviewers without authorship")
+		return picker.fallback.Pick(ctx, pullRequest, candidates, n)
+	}
+
+	authors := make([]gh.Collaborator, 0)
+	others := make([]gh.Collaborator, 0)
+
+	for _, candidate := range sortedByLogin(candidates) {
+		if authorship[candidate.Login] > 0 {
+			authors = append(authors, candidate)
+		} else {
+			others = append(others, candidate)
+		}
+	}
+
+	sort.SliceStable(authors, func(i, j int) bool {
+		return authorship[authors[i].Login] > authorship[authors[j].Login]
+	})
+
+	picked := authors[:minInt(n, len(authors))]
+
+	if len(picked) < n && len(others) > 0 {
+		othersPicked, err := picker.fallback.Pick(ctx, pullRequest, others, n-len(picked))
+		if err != nil {
+			return nil, err
+		}
+
+		picked = append(picked, othersPicked...)
+	}
+
+	return picked, nil
+}
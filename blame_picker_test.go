@@ -0,0 +1,85 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blamewarrior/hooks"
+	bw "github.com/blamewarrior/hooks/blamewarrior"
+	gh "github.com/blamewarrior/hooks/github"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlamePicker_Pick(t *testing.T) {
+	pullRequest := &bw.PullRequest{RepositoryName: "blamewarrior/hooks", Number: 1347}
+
+	results := []struct {
+		Authorship map[string]int
+		N          int
+		Expected   []string
+	}{
+		{map[string]int{"bob": 3, "dave": 10}, 1, []string{"dave"}},
+		{map[string]int{"bob": 3, "dave": 10}, 2, []string{"dave", "bob"}},
+		{map[string]int{"bob": 3, "carol": 3}, 2, []string{"bob", "carol"}},
+		{map[string]int{"dave": 10, "eve": 20}, 3, []string{"dave", "alice", "bob"}},
+		{nil, 1, []string{"alice"}},
+	}
+
+	for _, result := range results {
+		blame := new(BlameMock)
+		blame.On("Authorship", mock.Anything, "blamewarrior/hooks", 1347).Return(result.Authorship, nil)
+
+		picker := hooks.NewBlamePicker(blame, hooks.NewRoundRobinPicker())
+
+		reviewers, err := picker.Pick(context.Background(), pullRequest, testCandidates, result.N)
+		require.NoError(t, err)
+
+		assert.Equal(t, result.Expected, logins(reviewers), "authorship %v", result.Authorship)
+	}
+}
+
+func TestBlamePicker_Pick_Error(t *testing.T) {
+	pullRequest := &bw.PullRequest{RepositoryName: "blamewarrior/hooks", Number: 1347}
+
+	blame := new(BlameMock)
+	blame.On("Authorship", mock.Anything, "blamewarrior/hooks", 1347).Return(nil, errors.New("rate limit"))
+
+	picker := hooks.NewBlamePicker(blame, hooks.NewRoundRobinPicker())
+
+	// reviewers are still picked, just not by authorship
+	reviewers, err := picker.Pick(context.Background(), pullRequest, testCandidates, 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"alice", "bob"}, logins(reviewers))
+}
+
+type BlameMock struct {
+	mock.Mock
+}
+
+func (m *BlameMock) Authorship(ctx gh.Context, repoFullName string, pullNumber int) (map[string]int, error) {
+	args := m.Called(ctx, repoFullName, pullNumber)
+
+	authorship, _ := args.Get(0).(map[string]int)
+
+	return authorship, args.Error(1)
+}
@@ -28,11 +28,13 @@ type PullRequest struct {
 	Body           string            `json:"body"`
 	RepositoryName string            `json:"repository_name"`
 	Reviewers      []gh.Collaborator `json:"reviewers"`
+	RequestedTeams []gh.Team         `json:"requested_teams"`
 	Number         int               `json:"number"`
 	State          string            `json:"state"`
 	CreatedAt      *time.Time        `json:"opened_at"`
 	ClosedAt       *time.Time        `json:"closed_at"`
 	OwnerId        int               `json:"owner_id"`
+	OwnerLogin     string            `json:"-"`
 	Commits        int               `json:"commits"`
 	Additions      int               `json:"additions"`
 	Deletions      int               `json:"deletions"`
@@ -74,9 +76,120 @@ func NewPullRequestFromGithubHook(ghPullRequestHook *gh.GithubPullRequestHook) *
 		Deletions:      *ghPullRequestHook.PullRequest.Deletions,
 		RepositoryName: ghPullRequestHook.Repository.FullName,
 		OwnerId:        *ghPullRequestHook.PullRequest.User.ID,
+		OwnerLogin:     ghPullRequestHook.PullRequest.User.GetLogin(),
 	}
 
 	pullRequest.Reviewers = ghPullRequestHook.RequestedReviewers
+	pullRequest.RequestedTeams = ghPullRequestHook.RequestedTeams
 
 	return pullRequest
 }
+
+// Review is a pull request review submitted, edited or dismissed on GitHub.
+type Review struct {
+	Id                int        `json:"id"`
+	Action            string     `json:"action"`
+	State             string     `json:"state"`
+	Body              string     `json:"body"`
+	RepositoryName    string     `json:"repository_name"`
+	PullRequestNumber int        `json:"pull_request_number"`
+	ReviewerId        int        `json:"reviewer_id"`
+	ReviewerLogin     string     `json:"reviewer_login"`
+	CommitId          string     `json:"commit_id"`
+	SubmittedAt       *time.Time `json:"submitted_at"`
+}
+
+func NewReviewFromGithubHook(ghReviewHook *gh.GithubPullRequestReviewHook) *Review {
+	return &Review{
+		Id:                ghReviewHook.Review.GetID(),
+		Action:            ghReviewHook.Action,
+		State:             ghReviewHook.Review.GetState(),
+		Body:              ghReviewHook.Review.GetBody(),
+		RepositoryName:    ghReviewHook.Repository.FullName,
+		PullRequestNumber: ghReviewHook.PullRequest.GetNumber(),
+		ReviewerId:        ghReviewHook.Review.GetUser().GetID(),
+		ReviewerLogin:     ghReviewHook.Review.GetUser().GetLogin(),
+		CommitId:          ghReviewHook.Review.GetCommitID(),
+		SubmittedAt:       ghReviewHook.Review.SubmittedAt,
+	}
+}
+
+// ReviewComment is a pull request review comment created, edited or deleted on GitHub.
+type ReviewComment struct {
+	Id                int        `json:"id"`
+	Action            string     `json:"action"`
+	Body              string     `json:"body"`
+	RepositoryName    string     `json:"repository_name"`
+	PullRequestNumber int        `json:"pull_request_number"`
+	AuthorId          int        `json:"author_id"`
+	AuthorLogin       string     `json:"author_login"`
+	InReplyTo         int        `json:"in_reply_to"`
+	Path              string     `json:"path"`
+	Position          int        `json:"position"`
+	CommitId          string     `json:"commit_id"`
+	CreatedAt         *time.Time `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+}
+
+func NewReviewCommentFromGithubHook(ghCommentHook *gh.GithubPullRequestReviewCommentHook) *ReviewComment {
+	comment := ghCommentHook.Comment
+
+	return &ReviewComment{
+		Id:                derefInt(comment.ID),
+		Action:            ghCommentHook.Action,
+		Body:              derefString(comment.Body),
+		RepositoryName:    ghCommentHook.Repository.FullName,
+		PullRequestNumber: ghCommentHook.PullRequest.GetNumber(),
+		AuthorId:          comment.User.GetID(),
+		AuthorLogin:       comment.User.GetLogin(),
+		InReplyTo:         derefInt(comment.InReplyTo),
+		Path:              derefString(comment.Path),
+		Position:          derefInt(comment.Position),
+		CommitId:          derefString(comment.CommitID),
+		CreatedAt:         comment.CreatedAt,
+		UpdatedAt:         comment.UpdatedAt,
+	}
+}
+
+// Comment is a pull request conversation comment created, edited or deleted on GitHub.
+type Comment struct {
+	Id                int        `json:"id"`
+	Action            string     `json:"action"`
+	Body              string     `json:"body"`
+	RepositoryName    string     `json:"repository_name"`
+	PullRequestNumber int        `json:"pull_request_number"`
+	AuthorId          int        `json:"author_id"`
+	AuthorLogin       string     `json:"author_login"`
+	CreatedAt         *time.Time `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+}
+
+func NewCommentFromGithubHook(ghCommentHook *gh.GithubIssueCommentHook) *Comment {
+	return &Comment{
+		Id:                ghCommentHook.Comment.GetID(),
+		Action:            ghCommentHook.Action,
+		Body:              ghCommentHook.Comment.GetBody(),
+		RepositoryName:    ghCommentHook.Repository.FullName,
+		PullRequestNumber: ghCommentHook.Issue.GetNumber(),
+		AuthorId:          ghCommentHook.Comment.User.GetID(),
+		AuthorLogin:       ghCommentHook.Comment.User.GetLogin(),
+		CreatedAt:         ghCommentHook.Comment.CreatedAt,
+		UpdatedAt:         ghCommentHook.Comment.UpdatedAt,
+	}
+}
+
+func derefInt(i *int) int {
+	if i == nil {
+		return 0
+	}
+
+	return *i
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
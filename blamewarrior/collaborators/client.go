@@ -17,44 +17,53 @@ package collaborators
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	gh "github.com/blamewarrior/hooks/github"
 )
 
+// DefaultTimeout limits the time a single request to collaborators service may take.
+const DefaultTimeout = 10 * time.Second
+
 type Client interface {
-	FetchCollaborators(repositoryFullName string) error
-	ListCollaborator(repositoryFullName string) ([]gh.Collaborator, error)
-	AddCollaborator(repositoryFullName string, collaborator *gh.Collaborator) error
-	EditCollaborator(repositoryFullName string, collaborator *gh.Collaborator) error
-	DeleteCollaborator(repositoryFullName, login string) error
+	FetchCollaborators(ctx context.Context, repositoryFullName string) error
+	ListCollaborator(ctx context.Context, repositoryFullName string) ([]gh.Collaborator, error)
+	AddCollaborator(ctx context.Context, repositoryFullName string, collaborator *gh.Collaborator) error
+	EditCollaborator(ctx context.Context, repositoryFullName string, collaborator *gh.Collaborator) error
+	DeleteCollaborator(ctx context.Context, repositoryFullName, login string) error
 }
 
 type CollaboratorsClient struct {
 	BaseURL string
-	c       *http.Client
+	// HTTPClient is used to make requests to collaborators service, its Timeout limits the time
+	// a single request may take
+	HTTPClient *http.Client
 }
 
 func NewClient() *CollaboratorsClient {
 	client := &CollaboratorsClient{
-		BaseURL: "https://blamewarrior.com",
-		c:       http.DefaultClient,
+		BaseURL:    "https://blamewarrior.com",
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
 	}
 
 	return client
 }
 
-func (client *CollaboratorsClient) FetchCollaborators(repositoryFullName string) error {
+func (client *CollaboratorsClient) FetchCollaborators(ctx context.Context, repositoryFullName string) error {
 	requestUrl := fmt.Sprintf("%s/%s/collaborators", client.BaseURL, repositoryFullName)
 
-	response, err := client.c.Get(requestUrl)
+	response, err := client.do(ctx, "GET", requestUrl, nil)
 
 	if err != nil {
 		return err
 	}
+	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
 		return fmt.Errorf("Unable to get collaborators for %s, status_code=%d", repositoryFullName, response.StatusCode)
@@ -96,15 +105,15 @@ func (client *CollaboratorsClient) FetchCollaborators(repositoryFullName string)
 // 	return nil
 // }
 
-func (client *CollaboratorsClient) ListCollaborator(repositoryFullName string) ([]gh.Collaborator, error) {
-
+func (client *CollaboratorsClient) ListCollaborator(ctx context.Context, repositoryFullName string) ([]gh.Collaborator, error) {
 	requestUrl := fmt.Sprintf("%s/%s/collaborators", client.BaseURL, repositoryFullName)
 
-	response, err := client.c.Get(requestUrl)
+	response, err := client.do(ctx, "GET", requestUrl, nil)
 
 	if err != nil {
 		return nil, err
 	}
+	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("Unable to get collaborators for %s, status_code=%d", repositoryFullName, response.StatusCode)
@@ -125,7 +134,7 @@ func (client *CollaboratorsClient) ListCollaborator(repositoryFullName string) (
 	return collaborators, nil
 }
 
-func (client *CollaboratorsClient) AddCollaborator(repositoryFullName string, collaborator *gh.Collaborator) error {
+func (client *CollaboratorsClient) AddCollaborator(ctx context.Context, repositoryFullName string, collaborator *gh.Collaborator) error {
 	b, err := json.Marshal(collaborator)
 	if err != nil {
 		return err
@@ -133,11 +142,12 @@ func (client *CollaboratorsClient) AddCollaborator(repositoryFullName string, co
 
 	requestUrl := fmt.Sprintf("%s/%s/collaborators", client.BaseURL, repositoryFullName)
 
-	response, err := client.c.Post(requestUrl, "application/json", bytes.NewBuffer(b))
+	response, err := client.do(ctx, "POST", requestUrl, bytes.NewBuffer(b))
 
 	if err != nil {
 		return err
 	}
+	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusCreated {
 		return fmt.Errorf("Unable to add collaborator for %s, status_code=%d", repositoryFullName, response.StatusCode)
@@ -146,7 +156,7 @@ func (client *CollaboratorsClient) AddCollaborator(repositoryFullName string, co
 	return nil
 }
 
-func (client *CollaboratorsClient) EditCollaborator(repositoryFullName string, collaborator *gh.Collaborator) error {
+func (client *CollaboratorsClient) EditCollaborator(ctx context.Context, repositoryFullName string, collaborator *gh.Collaborator) error {
 	b, err := json.Marshal(collaborator)
 	if err != nil {
 		return err
@@ -154,16 +164,12 @@ func (client *CollaboratorsClient) EditCollaborator(repositoryFullName string, c
 
 	requestUrl := fmt.Sprintf("%s/%s/collaborators", client.BaseURL, repositoryFullName)
 
-	req, err := http.NewRequest("PUT", requestUrl, bytes.NewBuffer(b))
-	if err != nil {
-		return err
-	}
-
-	response, err := client.c.Do(req)
+	response, err := client.do(ctx, "PUT", requestUrl, bytes.NewBuffer(b))
 
 	if err != nil {
 		return err
 	}
+	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
 		return fmt.Errorf("Unable to edit collaborator for %s, status_code=%d", repositoryFullName, response.StatusCode)
@@ -172,22 +178,31 @@ func (client *CollaboratorsClient) EditCollaborator(repositoryFullName string, c
 	return nil
 }
 
-func (client *CollaboratorsClient) DeleteCollaborator(repositoryFullName, login string) error {
+func (client *CollaboratorsClient) DeleteCollaborator(ctx context.Context, repositoryFullName, login string) error {
 	requestUrl := fmt.Sprintf("%s/%s/collaborators/%s", client.BaseURL, repositoryFullName, login)
 
-	req, err := http.NewRequest("DELETE", requestUrl, nil)
-	if err != nil {
-		return err
-	}
-
-	response, err := client.c.Do(req)
+	response, err := client.do(ctx, "DELETE", requestUrl, nil)
 
 	if err != nil {
 		return err
 	}
+	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusNoContent {
 		return fmt.Errorf("Unable to delete collaborator for %s, status_code=%d", repositoryFullName, response.StatusCode)
 	}
 	return nil
 }
+
+func (client *CollaboratorsClient) do(ctx context.Context, method, requestUrl string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, requestUrl, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return client.HTTPClient.Do(req.WithContext(ctx))
+}
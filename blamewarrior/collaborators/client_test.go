@@ -1,6 +1,7 @@
 package collaborators_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -42,7 +43,7 @@ func TestListCollaborator(t *testing.T) {
 		client := collaborators.NewClient()
 		client.BaseURL = testAPIEndpoint
 
-		collaborators, err := client.ListCollaborator("blamewarrior/test_repo")
+		collaborators, err := client.ListCollaborator(context.Background(), "blamewarrior/test_repo")
 
 		assert.Equal(t, result.ResponseError, err)
 		assert.Equal(t, result.Collaborators, collaborators)
@@ -79,7 +80,7 @@ func TestAddCollaborator(t *testing.T) {
 		client := collaborators.NewClient()
 		client.BaseURL = testAPIEndpoint
 
-		err := client.AddCollaborator("blamewarrior/test_repo", result.Collaborator)
+		err := client.AddCollaborator(context.Background(), "blamewarrior/test_repo", result.Collaborator)
 		assert.Equal(t, result.ResponseError, err)
 
 		teardown()
@@ -114,7 +115,7 @@ func TestEditCollaborator(t *testing.T) {
 		client := collaborators.NewClient()
 		client.BaseURL = testAPIEndpoint
 
-		err := client.EditCollaborator("blamewarrior/test_repo", result.Collaborator)
+		err := client.EditCollaborator(context.Background(), "blamewarrior/test_repo", result.Collaborator)
 		assert.Equal(t, result.ResponseError, err)
 
 		teardown()
@@ -144,7 +145,7 @@ func TestDeleteCollaborator(t *testing.T) {
 		client := collaborators.NewClient()
 		client.BaseURL = testAPIEndpoint
 
-		err := client.DeleteCollaborator("blamewarrior/test_repo", result.Collaborator.Login)
+		err := client.DeleteCollaborator(context.Background(), "blamewarrior/test_repo", result.Collaborator.Login)
 		assert.Equal(t, result.ResponseError, err)
 
 		teardown()
@@ -157,3 +158,21 @@ func setup() (baseURL string, mux *http.ServeMux, teardown func()) {
 
 	return server.URL, mux, server.Close
 }
+
+func TestListCollaborator_Canceled(t *testing.T) {
+	testAPIEndpoint, mux, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/blamewarrior/test_repo/collaborators", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+
+	client := collaborators.NewClient()
+	client.BaseURL = testAPIEndpoint
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.ListCollaborator(ctx, "blamewarrior/test_repo")
+	require.Error(t, err)
+}
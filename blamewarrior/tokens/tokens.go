@@ -16,14 +16,19 @@
 package tokens
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 )
 
+// DefaultTimeout limits the time a single request to users service may take.
+const DefaultTimeout = 10 * time.Second
+
 type Client interface {
-	GetToken(nickname string) (token string, err error)
+	GetToken(ctx context.Context, nickname string) (token string, err error)
 }
 
 type Response struct {
@@ -32,18 +37,26 @@ type Response struct {
 
 type TokenClient struct {
 	BaseURL string
-	c       *http.Client
+	// HTTPClient is used to make requests to users service, its Timeout limits the time
+	// a single request may take
+	HTTPClient *http.Client
 
 	nickname string
 }
 
-func (client *TokenClient) GetToken(nickname string) (token string, err error) {
+func (client *TokenClient) GetToken(ctx context.Context, nickname string) (token string, err error) {
+
+	req, err := http.NewRequest("GET", client.BaseURL+"/users/"+nickname, nil)
+	if err != nil {
+		return "", err
+	}
 
-	resp, err := client.c.Get(client.BaseURL + "/users/" + nickname)
+	resp, err := client.HTTPClient.Do(req.WithContext(ctx))
 
 	if err != nil {
 		return "", fmt.Errorf("impossible to get data for %s: %s", nickname, err)
 	}
+	defer resp.Body.Close()
 
 	b, err := ioutil.ReadAll(resp.Body)
 
@@ -74,8 +87,8 @@ func (client *TokenClient) GetToken(nickname string) (token string, err error) {
 
 func NewTokenClient() *TokenClient {
 	client := &TokenClient{
-		BaseURL: "https://blamewarrior.com",
-		c:       http.DefaultClient,
+		BaseURL:    "https://blamewarrior.com",
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
 	}
 
 	return client
@@ -16,9 +16,11 @@
 package tokens_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/blamewarrior/hooks/blamewarrior/tokens"
 	"github.com/stretchr/testify/assert"
@@ -37,7 +39,7 @@ func TestGetToken(t *testing.T) {
 	client := tokens.NewTokenClient()
 	client.BaseURL = testAPIEndpoint
 
-	token, err := client.GetToken("blamewarrior")
+	token, err := client.GetToken(context.Background(), "blamewarrior")
 
 	require.NoError(t, err)
 
@@ -45,6 +47,57 @@ func TestGetToken(t *testing.T) {
 
 }
 
+func TestGetToken_Timeout(t *testing.T) {
+	testAPIEndpoint, mux, teardown := setup()
+
+	defer teardown()
+
+	mux.HandleFunc("/users/blamewarrior", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+
+	client := tokens.NewTokenClient()
+	client.BaseURL = testAPIEndpoint
+	client.HTTPClient.Timeout = 10 * time.Millisecond
+
+	_, err := client.GetToken(context.Background(), "blamewarrior")
+
+	assert.Error(t, err)
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestGetToken_Transport(t *testing.T) {
+	testAPIEndpoint, mux, teardown := setup()
+
+	defer teardown()
+
+	mux.HandleFunc("/users/blamewarrior", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(userResponse))
+	})
+
+	var requested []string
+
+	client := tokens.NewTokenClient()
+	client.BaseURL = testAPIEndpoint
+	client.HTTPClient.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.Path)
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	_, err := client.GetToken(context.Background(), "blamewarrior")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/users/blamewarrior"}, requested)
+}
+
 func setup() (baseURL string, mux *http.ServeMux, teardown func()) {
 	mux = http.NewServeMux()
 	server := httptest.NewServer(mux)
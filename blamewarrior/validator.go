@@ -56,7 +56,7 @@ func (v *Validator) populateMessages(defaultMsg string, msgArgs ...interface{})
 	var msg string
 
 	if len(msgArgs) == 0 || msgArgs == nil {
-		msg = fmt.Sprintf(defaultMsg)
+		msg = defaultMsg
 	}
 
 	if len(msgArgs) == 1 {
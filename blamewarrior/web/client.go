@@ -17,32 +17,42 @@ package web
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	bw "github.com/blamewarrior/hooks/blamewarrior"
 )
 
+// DefaultTimeout limits the time a single request to BlameWarrior may take.
+const DefaultTimeout = 10 * time.Second
+
 type Client interface {
-	ProcessPullRequest(pullRequest *bw.PullRequest) (err error)
+	ProcessPullRequest(ctx context.Context, pullRequest *bw.PullRequest) (err error)
+	ProcessReview(ctx context.Context, review *bw.Review) (err error)
+	ProcessReviewComment(ctx context.Context, comment *bw.ReviewComment) (err error)
+	ProcessComment(ctx context.Context, comment *bw.Comment) (err error)
 }
 
 type WebClient struct {
 	BaseURL string
-	c       *http.Client
+	// HTTPClient is used to make requests to BlameWarrior, its Timeout limits the time
+	// a single request may take
+	HTTPClient *http.Client
 }
 
 func NewClient() *WebClient {
 	client := &WebClient{
-		BaseURL: "https://blamewarrior.com",
-		c:       http.DefaultClient,
+		BaseURL:    "https://blamewarrior.com",
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
 	}
 
 	return client
 }
 
-func (client *WebClient) ProcessPullRequest(pullRequest *bw.PullRequest) (err error) {
+func (client *WebClient) ProcessPullRequest(ctx context.Context, pullRequest *bw.PullRequest) (err error) {
 
 	repositoryFullName := pullRequest.RepositoryName
 
@@ -54,7 +64,7 @@ func (client *WebClient) ProcessPullRequest(pullRequest *bw.PullRequest) (err er
 		return err
 	}
 
-	response, err := client.c.Post(requestUrl, "application/json", bytes.NewBuffer(b))
+	response, err := client.post(ctx, requestUrl, b)
 
 	if err != nil {
 		return err
@@ -67,3 +77,98 @@ func (client *WebClient) ProcessPullRequest(pullRequest *bw.PullRequest) (err er
 
 	return nil
 }
+
+func (client *WebClient) ProcessReview(ctx context.Context, review *bw.Review) (err error) {
+
+	repositoryFullName := review.RepositoryName
+
+	requestUrl := fmt.Sprintf("%s/api/%s/pull_requests/%d/reviews/process", client.BaseURL, repositoryFullName, review.PullRequestNumber)
+
+	b, err := json.Marshal(review)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := client.post(ctx, requestUrl, b)
+
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusNoContent {
+
+		return fmt.Errorf("Impossible to process review for %s, status_code=%d", repositoryFullName, response.StatusCode)
+	}
+
+	return nil
+}
+
+func (client *WebClient) ProcessReviewComment(ctx context.Context, comment *bw.ReviewComment) (err error) {
+
+	repositoryFullName := comment.RepositoryName
+
+	requestUrl := fmt.Sprintf("%s/api/%s/pull_requests/%d/review_comments/process", client.BaseURL, repositoryFullName, comment.PullRequestNumber)
+
+	b, err := json.Marshal(comment)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := client.post(ctx, requestUrl, b)
+
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusNoContent {
+
+		return fmt.Errorf("Impossible to process review comment for %s, status_code=%d", repositoryFullName, response.StatusCode)
+	}
+
+	return nil
+}
+
+func (client *WebClient) ProcessComment(ctx context.Context, comment *bw.Comment) (err error) {
+
+	repositoryFullName := comment.RepositoryName
+
+	requestUrl := fmt.Sprintf("%s/api/%s/pull_requests/%d/comments/process", client.BaseURL, repositoryFullName, comment.PullRequestNumber)
+
+	b, err := json.Marshal(comment)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := client.post(ctx, requestUrl, b)
+
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusNoContent {
+
+		return fmt.Errorf("Impossible to process comment for %s, status_code=%d", repositoryFullName, response.StatusCode)
+	}
+
+	return nil
+}
+
+func (client *WebClient) post(ctx context.Context, requestUrl string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest("POST", requestUrl, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	response, err := client.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	return response, nil
+}
@@ -16,6 +16,7 @@
 package web_test
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -50,7 +51,7 @@ func TestProcessPullRequest(t *testing.T) {
 			bodyBytes, _ := ioutil.ReadAll(r.Body)
 
 			require.Equal(t,
-				"{\"id\":123,\"html_url\":\"\",\"title\":\"bug fixes\",\"body\":\"\",\"repository_name\":\"blamewarrior/test_repo\",\"reviewers\":[{\"id\":2,\"login\":\"test_user\",\"admin\":true}],\"number\":12,\"state\":\"open\",\"opened_at\":null,\"closed_at\":null,\"owner_id\":1,\"commits\":0,\"additions\":0,\"deletions\":0,\"review_comments\":null}",
+				"{\"id\":123,\"html_url\":\"\",\"title\":\"bug fixes\",\"body\":\"\",\"repository_name\":\"blamewarrior/test_repo\",\"reviewers\":[{\"id\":2,\"login\":\"test_user\",\"admin\":true}],\"requested_teams\":null,\"number\":12,\"state\":\"open\",\"opened_at\":null,\"closed_at\":null,\"owner_id\":1,\"commits\":0,\"additions\":0,\"deletions\":0,\"review_comments\":null}",
 				string(bodyBytes))
 
 			w.WriteHeader(result.ResponseStatus)
@@ -71,7 +72,147 @@ func TestProcessPullRequest(t *testing.T) {
 			},
 		}
 
-		err := client.ProcessPullRequest(pullRequest)
+		err := client.ProcessPullRequest(context.Background(), pullRequest)
+
+		assert.Equal(t, result.ResponseError, err)
+
+		teardown()
+	}
+}
+
+func TestProcessReview(t *testing.T) {
+
+	results := []struct {
+		ResponseStatus int
+		ResponseError  error
+	}{
+		{ResponseStatus: http.StatusNoContent, ResponseError: nil},
+		{ResponseStatus: http.StatusNotFound, ResponseError: errors.New("Impossible to process review for blamewarrior/test_repo, status_code=404")},
+	}
+
+	for _, result := range results {
+		testAPIEndpoint, mux, teardown := setup()
+
+		mux.HandleFunc("/api/blamewarrior/test_repo/pull_requests/12/reviews/process", func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "POST", r.Method)
+
+			bodyBytes, _ := ioutil.ReadAll(r.Body)
+
+			require.Equal(t,
+				"{\"id\":80,\"action\":\"submitted\",\"state\":\"approved\",\"body\":\"\",\"repository_name\":\"blamewarrior/test_repo\",\"pull_request_number\":12,\"reviewer_id\":2,\"reviewer_login\":\"test_user\",\"commit_id\":\"\",\"submitted_at\":null}",
+				string(bodyBytes))
+
+			w.WriteHeader(result.ResponseStatus)
+		})
+
+		client := web.NewClient()
+		client.BaseURL = testAPIEndpoint
+
+		review := &bw.Review{
+			Id:                80,
+			Action:            "submitted",
+			State:             "approved",
+			RepositoryName:    "blamewarrior/test_repo",
+			PullRequestNumber: 12,
+			ReviewerId:        2,
+			ReviewerLogin:     "test_user",
+		}
+
+		err := client.ProcessReview(context.Background(), review)
+
+		assert.Equal(t, result.ResponseError, err)
+
+		teardown()
+	}
+}
+
+func TestProcessReviewComment(t *testing.T) {
+
+	results := []struct {
+		ResponseStatus int
+		ResponseError  error
+	}{
+		{ResponseStatus: http.StatusNoContent, ResponseError: nil},
+		{ResponseStatus: http.StatusNotFound, ResponseError: errors.New("Impossible to process review comment for blamewarrior/test_repo, status_code=404")},
+	}
+
+	for _, result := range results {
+		testAPIEndpoint, mux, teardown := setup()
+
+		mux.HandleFunc("/api/blamewarrior/test_repo/pull_requests/12/review_comments/process", func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "POST", r.Method)
+
+			bodyBytes, _ := ioutil.ReadAll(r.Body)
+
+			require.Equal(t,
+				"{\"id\":10,\"action\":\"deleted\",\"body\":\"nit\",\"repository_name\":\"blamewarrior/test_repo\",\"pull_request_number\":12,\"author_id\":2,\"author_login\":\"test_user\",\"in_reply_to\":0,\"path\":\"main.go\",\"position\":1,\"commit_id\":\"\",\"created_at\":null,\"updated_at\":null}",
+				string(bodyBytes))
+
+			w.WriteHeader(result.ResponseStatus)
+		})
+
+		client := web.NewClient()
+		client.BaseURL = testAPIEndpoint
+
+		comment := &bw.ReviewComment{
+			Id:                10,
+			Action:            "deleted",
+			Body:              "nit",
+			RepositoryName:    "blamewarrior/test_repo",
+			PullRequestNumber: 12,
+			AuthorId:          2,
+			AuthorLogin:       "test_user",
+			Path:              "main.go",
+			Position:          1,
+		}
+
+		err := client.ProcessReviewComment(context.Background(), comment)
+
+		assert.Equal(t, result.ResponseError, err)
+
+		teardown()
+	}
+}
+
+func TestProcessComment(t *testing.T) {
+
+	results := []struct {
+		ResponseStatus int
+		ResponseError  error
+	}{
+		{ResponseStatus: http.StatusNoContent, ResponseError: nil},
+		{ResponseStatus: http.StatusNotFound, ResponseError: errors.New("Impossible to process comment for blamewarrior/test_repo, status_code=404")},
+	}
+
+	for _, result := range results {
+		testAPIEndpoint, mux, teardown := setup()
+
+		mux.HandleFunc("/api/blamewarrior/test_repo/pull_requests/12/comments/process", func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "POST", r.Method)
+
+			bodyBytes, _ := ioutil.ReadAll(r.Body)
+
+			require.Equal(t,
+				"{\"id\":10,\"action\":\"created\",\"body\":\"LGTM\",\"repository_name\":\"blamewarrior/test_repo\",\"pull_request_number\":12,\"author_id\":2,\"author_login\":\"test_user\",\"created_at\":null,\"updated_at\":null}",
+				string(bodyBytes))
+
+			w.WriteHeader(result.ResponseStatus)
+		})
+
+		client := web.NewClient()
+		client.BaseURL = testAPIEndpoint
+
+		comment := &bw.Comment{
+			Id:                10,
+			Action:            "created",
+			Body:              "LGTM",
+			RepositoryName:    "blamewarrior/test_repo",
+			PullRequestNumber: 12,
+			AuthorId:          2,
+			AuthorLogin:       "test_user",
+		}
+
+		err := client.ProcessComment(context.Background(), comment)
 
 		assert.Equal(t, result.ResponseError, err)
 
@@ -0,0 +1,453 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blamewarrior/hooks"
+	"github.com/blamewarrior/hooks/github"
+	"github.com/blamewarrior/hooks/logging"
+)
+
+// Config holds cmd/api settings. It is read from JSON file, environment variables
+// take precedence over the file, see LoadConfig.
+type Config struct {
+	ListenAddr string `json:"listen_addr"`
+	// Hostname is the host GitHub sends deliveries to, either hostname served
+	// over https or base URL, e.g. http://hooks.internal:8080
+	Hostname string `json:"hostname"`
+	LogLevel string `json:"log_level"`
+
+	Redis    RedisConfig    `json:"redis"`
+	Services ServicesConfig `json:"services"`
+	GitHub   GitHubConfig   `json:"github"`
+	Timeouts TimeoutsConfig `json:"timeouts"`
+
+	DeliveriesWindow Duration `json:"deliveries_window"`
+	// AsyncWorkers is the number of workers processing deliveries asynchronously,
+	// deliveries are processed synchronously if it is 0
+	AsyncWorkers int `json:"async_workers"`
+
+	Reviewers ReviewersConfig `json:"reviewers"`
+	// Repositories overrides reviewers settings for particular repositories
+	Repositories map[string]RepositoryConfig `json:"repositories"`
+}
+
+type RedisConfig struct {
+	Addr     string `json:"addr"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
+}
+
+// ServicesConfig holds base URLs of BlameWarrior services
+type ServicesConfig struct {
+	TokensURL        string `json:"tokens_url"`
+	CollaboratorsURL string `json:"collaborators_url"`
+	WebURL           string `json:"web_url"`
+}
+
+// GitHubConfig points to GitHub or GitHub Enterprise Server API
+type GitHubConfig struct {
+	APIURL string `json:"api_url"`
+	// UploadURL is derived from APIURL if empty
+	UploadURL string `json:"upload_url"`
+	// AppID enables authenticating as GitHub App instead of using BlameWarrior
+	// users tokens, PrivateKeyPath is required then
+	AppID          int    `json:"app_id"`
+	PrivateKeyPath string `json:"private_key_path"`
+	// Installations maps repositories or owners to app installation IDs,
+	// missing ones are looked up via GitHub API
+	Installations map[string]int `json:"installations"`
+}
+
+type TimeoutsConfig struct {
+	GitHub       Duration `json:"github"`
+	BlameWarrior Duration `json:"blamewarrior"`
+	Shutdown     Duration `json:"shutdown"`
+}
+
+type ReviewersConfig struct {
+	Strategy          string `json:"strategy"`
+	RequiredReviewers int    `json:"required_reviewers"`
+}
+
+// RepositoryConfig overrides reviewers settings for repository, zero values fall
+// back to defaults.
+type RepositoryConfig struct {
+	Strategy          string `json:"strategy"`
+	RequiredReviewers int    `json:"required_reviewers"`
+	// Team is the slug of a team requested to review every pull request
+	Team string `json:"team"`
+}
+
+// Duration is time.Duration read from JSON string, e.g. "10s"
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("expected duration string, e.g. \"10s\", got %s", b)
+	}
+
+	d.Duration, err = time.ParseDuration(s)
+
+	return err
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// DefaultConfig returns settings used unless overridden.
+func DefaultConfig() *Config {
+	return &Config{
+		ListenAddr: ":8080",
+		LogLevel:   "info",
+		Redis: RedisConfig{
+			Addr: "localhost:6379",
+		},
+		Services: ServicesConfig{
+			TokensURL:        "https://blamewarrior.com",
+			CollaboratorsURL: "https://blamewarrior.com",
+			WebURL:           "https://blamewarrior.com",
+		},
+		GitHub: GitHubConfig{
+			APIURL: "https://api.github.com/",
+		},
+		Timeouts: TimeoutsConfig{
+			GitHub:       Duration{10 * time.Second},
+			BlameWarrior: Duration{10 * time.Second},
+			Shutdown:     Duration{30 * time.Second},
+		},
+		DeliveriesWindow: Duration{24 * time.Hour},
+		Reviewers: ReviewersConfig{
+			Strategy:          "random-admin",
+			RequiredReviewers: 1,
+		},
+		Repositories: make(map[string]RepositoryConfig),
+	}
+}
+
+// LoadConfig reads config from JSON file at path if set, overrides it with
+// environment variables looked up with getenv and validates the result.
+func LoadConfig(path string, getenv func(string) string) (*Config, error) {
+	config := DefaultConfig()
+
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %s", err)
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.DisallowUnknownFields()
+
+		if err = decoder.Decode(config); err != nil {
+			return nil, fmt.Errorf("malformed config %s: %s", path, err)
+		}
+	}
+
+	if err := config.applyEnv(getenv); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (config *Config) applyEnv(getenv func(string) string) error {
+	var errs ConfigError
+
+	setString := func(env string, dst *string) {
+		if v := getenv(env); v != "" {
+			*dst = v
+		}
+	}
+
+	setInt := func(env string, dst *int) {
+		if v := getenv(env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s must be a number, got %q", env, v))
+				return
+			}
+			*dst = n
+		}
+	}
+
+	setDuration := func(env string, dst *Duration) {
+		if v := getenv(env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s must be a duration, e.g. 10s, got %q", env, v))
+				return
+			}
+			dst.Duration = d
+		}
+	}
+
+	setRepositories := func(env string, set func(repo *RepositoryConfig, value string) error) {
+		settings, err := parseRepositorySettings(getenv(env))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", env, err))
+			return
+		}
+
+		for repoFullName, value := range settings {
+			repo := config.Repositories[repoFullName]
+			if err := set(&repo, value); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s: %s", env, repoFullName, err))
+				continue
+			}
+			config.Repositories[repoFullName] = repo
+		}
+	}
+
+	setString("LISTEN_ADDR", &config.ListenAddr)
+	setString("BW_HOST", &config.Hostname)
+	setString("LOG_LEVEL", &config.LogLevel)
+
+	if host, port := getenv("REDIS_HOST"), getenv("REDIS_PORT"); host != "" || port != "" {
+		defaultHost, defaultPort, _ := net.SplitHostPort(config.Redis.Addr)
+		if host == "" {
+			host = defaultHost
+		}
+		if port == "" {
+			port = defaultPort
+		}
+		config.Redis.Addr = net.JoinHostPort(host, port)
+	}
+	setString("REDIS_PASSWORD", &config.Redis.Password)
+	setInt("REDIS_DB", &config.Redis.DB)
+
+	setString("TOKENS_URL", &config.Services.TokensURL)
+	setString("COLLABORATORS_URL", &config.Services.CollaboratorsURL)
+	setString("WEB_URL", &config.Services.WebURL)
+	setString("GITHUB_API_URL", &config.GitHub.APIURL)
+	setString("GITHUB_UPLOAD_URL", &config.GitHub.UploadURL)
+	setInt("GITHUB_APP_ID", &config.GitHub.AppID)
+	setString("GITHUB_APP_PRIVATE_KEY_PATH", &config.GitHub.PrivateKeyPath)
+
+	if installations, err := parseRepositorySettings(getenv("GITHUB_APP_INSTALLATIONS")); err != nil {
+		errs = append(errs, fmt.Sprintf("GITHUB_APP_INSTALLATIONS: %s", err))
+	} else {
+		for name, value := range installations {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("GITHUB_APP_INSTALLATIONS: %s: installation ID must be a number, got %q", name, value))
+				continue
+			}
+
+			if config.GitHub.Installations == nil {
+				config.GitHub.Installations = make(map[string]int)
+			}
+			config.GitHub.Installations[name] = id
+		}
+	}
+
+	setDuration("GITHUB_TIMEOUT", &config.Timeouts.GitHub)
+	setDuration("BW_TIMEOUT", &config.Timeouts.BlameWarrior)
+	setDuration("SHUTDOWN_TIMEOUT", &config.Timeouts.Shutdown)
+	setDuration("DELIVERIES_WINDOW", &config.DeliveriesWindow)
+
+	setInt("ASYNC_WORKERS", &config.AsyncWorkers)
+
+	setString("REVIEWER_STRATEGY", &config.Reviewers.Strategy)
+	setInt("REQUIRED_REVIEWERS", &config.Reviewers.RequiredReviewers)
+
+	if config.Repositories == nil {
+		config.Repositories = make(map[string]RepositoryConfig)
+	}
+
+	setRepositories("REVIEWER_STRATEGIES", func(repo *RepositoryConfig, value string) error {
+		repo.Strategy = value
+		return nil
+	})
+	setRepositories("REPOSITORY_REQUIRED_REVIEWERS", func(repo *RepositoryConfig, value string) (err error) {
+		if repo.RequiredReviewers, err = strconv.Atoi(value); err != nil {
+			return fmt.Errorf("required reviewers must be a number, got %q", value)
+		}
+		return nil
+	})
+	setRepositories("REPOSITORY_REVIEW_TEAMS", func(repo *RepositoryConfig, value string) error {
+		repo.Team = value
+		return nil
+	})
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// Validate checks whether settings are usable and reports all problems at once.
+func (config *Config) Validate() error {
+	var errs ConfigError
+
+	if config.ListenAddr == "" {
+		errs = append(errs, "listen_addr is required")
+	}
+
+	if config.Hostname == "" {
+		errs = append(errs, "hostname is required (set it in config file or BW_HOST)")
+	} else if err := urlHealthCheck(hooksBaseURL(config.Hostname))(); err != nil {
+		errs = append(errs, fmt.Sprintf("hostname: %s", err))
+	}
+
+	if _, err := logging.ParseLevel(config.LogLevel); err != nil {
+		errs = append(errs, fmt.Sprintf("log_level: %s", err))
+	}
+
+	if _, _, err := net.SplitHostPort(config.Redis.Addr); err != nil {
+		errs = append(errs, fmt.Sprintf("redis.addr must be host:port, got %q", config.Redis.Addr))
+	}
+
+	if config.Redis.DB < 0 {
+		errs = append(errs, fmt.Sprintf("redis.db must not be negative, got %d", config.Redis.DB))
+	}
+
+	for name, rawURL := range map[string]string{
+		"services.tokens_url":        config.Services.TokensURL,
+		"services.collaborators_url": config.Services.CollaboratorsURL,
+		"services.web_url":           config.Services.WebURL,
+	} {
+		if err := urlHealthCheck(rawURL)(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+
+	if _, err := github.NewAPIConfig(config.GitHub.APIURL, config.GitHub.UploadURL); err != nil {
+		errs = append(errs, fmt.Sprintf("github: %s", err))
+	}
+
+	switch {
+	case config.GitHub.AppID < 0:
+		errs = append(errs, fmt.Sprintf("github.app_id must not be negative, got %d", config.GitHub.AppID))
+	case config.GitHub.AppID > 0 && config.GitHub.PrivateKeyPath == "":
+		errs = append(errs, "github.private_key_path is required to authenticate as GitHub App")
+	case config.GitHub.AppID == 0 && (config.GitHub.PrivateKeyPath != "" || len(config.GitHub.Installations) > 0):
+		errs = append(errs, "github.app_id is required to authenticate as GitHub App")
+	}
+
+	for name, id := range config.GitHub.Installations {
+		if id <= 0 {
+			errs = append(errs, fmt.Sprintf("github.installations.%s must be positive, got %d", name, id))
+		}
+	}
+
+	for name, d := range map[string]Duration{
+		"timeouts.github":       config.Timeouts.GitHub,
+		"timeouts.blamewarrior": config.Timeouts.BlameWarrior,
+		"timeouts.shutdown":     config.Timeouts.Shutdown,
+		"deliveries_window":     config.DeliveriesWindow,
+	} {
+		if d.Duration <= 0 {
+			errs = append(errs, fmt.Sprintf("%s must be positive, got %s", name, d))
+		}
+	}
+
+	if config.AsyncWorkers < 0 {
+		errs = append(errs, fmt.Sprintf("async_workers must not be negative, got %d", config.AsyncWorkers))
+	}
+
+	if err := validateStrategy(config.Reviewers.Strategy); err != nil {
+		errs = append(errs, fmt.Sprintf("reviewers.strategy: %s", err))
+	}
+
+	if config.Reviewers.RequiredReviewers < 1 {
+		errs = append(errs, fmt.Sprintf("reviewers.required_reviewers must be at least 1, got %d", config.Reviewers.RequiredReviewers))
+	}
+
+	for repoFullName, repo := range config.Repositories {
+		if parts := strings.Split(repoFullName, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			errs = append(errs, fmt.Sprintf("repositories: expected owner/repo, got %q", repoFullName))
+		}
+
+		if repo.Strategy != "" {
+			if err := validateStrategy(repo.Strategy); err != nil {
+				errs = append(errs, fmt.Sprintf("repositories.%s.strategy: %s", repoFullName, err))
+			}
+		}
+
+		if repo.RequiredReviewers < 0 {
+			errs = append(errs, fmt.Sprintf("repositories.%s.required_reviewers must not be negative, got %d", repoFullName, repo.RequiredReviewers))
+		}
+	}
+
+	if len(errs) > 0 {
+		// map iteration order is random, keep errors stable
+		sort.Strings(errs)
+		return errs
+	}
+
+	return nil
+}
+
+// GitHubAPI returns settings to reach GitHub API with, the config is expected
+// to be validated.
+func (config *Config) GitHubAPI() github.APIConfig {
+	api, _ := github.NewAPIConfig(config.GitHub.APIURL, config.GitHub.UploadURL)
+	api.Timeout = config.Timeouts.GitHub.Duration
+
+	return api
+}
+
+func validateStrategy(strategy string) error {
+	_, err := hooks.NewReviewerPicker(strategy, nil, nil, nil)
+	return err
+}
+
+// ConfigError lists problems found in config
+type ConfigError []string
+
+func (errs ConfigError) Error() string {
+	return "invalid config: " + strings.Join(errs, "; ")
+}
+
+// parseRepositorySettings parses comma-separated "owner/repo=value" pairs
+func parseRepositorySettings(s string) (map[string]string, error) {
+	settings := make(map[string]string)
+
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expected owner/repo=value, got %q", pair)
+		}
+
+		settings[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,184 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	main "github.com/blamewarrior/hooks/cmd/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Example(t *testing.T) {
+	config, err := main.LoadConfig("config.example.json", env(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8080", config.ListenAddr)
+	assert.Equal(t, "hooks.blamewarrior.com", config.Hostname)
+	assert.Equal(t, "localhost:6379", config.Redis.Addr)
+	assert.Equal(t, 10*time.Second, config.Timeouts.GitHub.Duration)
+	assert.Equal(t, 24*time.Hour, config.DeliveriesWindow.Duration)
+	assert.Equal(t, main.RepositoryConfig{Strategy: "blame", RequiredReviewers: 2, Team: "core"}, config.Repositories["blamewarrior/hooks"])
+	assert.Equal(t, "https://api.github.com/", config.GitHubAPI().BaseURL.String())
+	assert.Equal(t, "https://uploads.github.com/", config.GitHubAPI().UploadURL.String())
+	assert.Equal(t, 10*time.Second, config.GitHubAPI().Timeout)
+}
+
+func TestLoadConfig_EnvOverrides(t *testing.T) {
+	config, err := main.LoadConfig("config.example.json", env(map[string]string{
+		"LISTEN_ADDR":                   ":9090",
+		"BW_HOST":                       "hooks.example.com",
+		"REDIS_HOST":                    "redis",
+		"REDIS_DB":                      "2",
+		"WEB_URL":                       "http://web:3000",
+		"GITHUB_API_URL":                "https://github.example.com/api/v3",
+		"GITHUB_UPLOAD_URL":             "https://uploads.example.com",
+		"GITHUB_TIMEOUT":                "5s",
+		"ASYNC_WORKERS":                 "4",
+		"REQUIRED_REVIEWERS":            "2",
+		"REVIEWER_STRATEGIES":           "octocat/hello-world=round-robin",
+		"REPOSITORY_REQUIRED_REVIEWERS": "blamewarrior/hooks=3",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9090", config.ListenAddr)
+	assert.Equal(t, "hooks.example.com", config.Hostname)
+	assert.Equal(t, "redis:6379", config.Redis.Addr)
+	assert.Equal(t, 2, config.Redis.DB)
+	assert.Equal(t, "http://web:3000", config.Services.WebURL)
+	assert.Equal(t, "https://blamewarrior.com", config.Services.TokensURL)
+	assert.Equal(t, "https://github.example.com/api/v3/", config.GitHubAPI().BaseURL.String())
+	assert.Equal(t, "https://uploads.example.com/", config.GitHubAPI().UploadURL.String())
+	assert.Equal(t, 5*time.Second, config.Timeouts.GitHub.Duration)
+	assert.Equal(t, 4, config.AsyncWorkers)
+	assert.Equal(t, 2, config.Reviewers.RequiredReviewers)
+	assert.Equal(t, map[string]main.RepositoryConfig{
+		"blamewarrior/hooks":  {Strategy: "blame", RequiredReviewers: 3, Team: "core"},
+		"octocat/hello-world": {Strategy: "round-robin"},
+	}, config.Repositories)
+}
+
+func TestLoadConfig_EnvOnly(t *testing.T) {
+	config, err := main.LoadConfig("", env(map[string]string{"BW_HOST": "hooks.example.com"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, main.DefaultConfig().Services, config.Services)
+	assert.Equal(t, "random-admin", config.Reviewers.Strategy)
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	f, err := ioutil.TempFile("", "config")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`{
+		"redis": {"addr": "localhost"},
+		"services": {"web_url": "blamewarrior.com"},
+		"github": {"upload_url": "uploads"},
+		"timeouts": {"github": "0s"},
+		"reviewers": {"strategy": "most-experienced", "required_reviewers": 1},
+		"repositories": {"hooks": {"required_reviewers": -1}}
+	}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = main.LoadConfig(f.Name(), env(nil))
+	require.Error(t, err)
+
+	assert.Equal(t, "invalid config: "+
+		`github: malformed GitHub upload URL: expected absolute http(s) URL, got "uploads"; `+
+		"hostname is required (set it in config file or BW_HOST); "+
+		`redis.addr must be host:port, got "localhost"; `+
+		`repositories.hooks.required_reviewers must not be negative, got -1; `+
+		`repositories: expected owner/repo, got "hooks"; `+
+		"reviewers.strategy: unsupported reviewer strategy most-experienced; "+
+		`services.web_url: expected absolute http(s) URL, got "blamewarrior.com"; `+
+		"timeouts.github must be positive, got 0s", err.Error())
+}
+
+func TestLoadConfig_GitHubApp(t *testing.T) {
+	config, err := main.LoadConfig("", env(map[string]string{
+		"BW_HOST":                     "hooks.example.com",
+		"GITHUB_APP_ID":               "1",
+		"GITHUB_APP_PRIVATE_KEY_PATH": "/etc/hooks/app.pem",
+		"GITHUB_APP_INSTALLATIONS":    "blamewarrior=42, octocat/hello-world=43",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, config.GitHub.AppID)
+	assert.Equal(t, "/etc/hooks/app.pem", config.GitHub.PrivateKeyPath)
+	assert.Equal(t, map[string]int{"blamewarrior": 42, "octocat/hello-world": 43}, config.GitHub.Installations)
+
+	_, err = main.LoadConfig("", env(map[string]string{
+		"BW_HOST":                  "hooks.example.com",
+		"GITHUB_APP_ID":            "1",
+		"GITHUB_APP_INSTALLATIONS": "blamewarrior=first,octocat=0",
+	}))
+	assert.EqualError(t, err, "invalid config: "+
+		`GITHUB_APP_INSTALLATIONS: blamewarrior: installation ID must be a number, got "first"`)
+
+	_, err = main.LoadConfig("", env(map[string]string{
+		"BW_HOST":                  "hooks.example.com",
+		"GITHUB_APP_ID":            "1",
+		"GITHUB_APP_INSTALLATIONS": "octocat=0",
+	}))
+	assert.EqualError(t, err, "invalid config: "+
+		"github.installations.octocat must be positive, got 0; "+
+		"github.private_key_path is required to authenticate as GitHub App")
+
+	_, err = main.LoadConfig("", env(map[string]string{
+		"BW_HOST":                     "hooks.example.com",
+		"GITHUB_APP_PRIVATE_KEY_PATH": "/etc/hooks/app.pem",
+	}))
+	assert.EqualError(t, err, "invalid config: github.app_id is required to authenticate as GitHub App")
+}
+
+func TestLoadConfig_MalformedEnv(t *testing.T) {
+	_, err := main.LoadConfig("", env(map[string]string{
+		"BW_HOST":                       "hooks.example.com",
+		"GITHUB_TIMEOUT":                "10",
+		"REPOSITORY_REQUIRED_REVIEWERS": "blamewarrior/hooks=two",
+	}))
+
+	assert.EqualError(t, err, "invalid config: "+
+		`GITHUB_TIMEOUT must be a duration, e.g. 10s, got "10"; `+
+		`REPOSITORY_REQUIRED_REVIEWERS: blamewarrior/hooks: required reviewers must be a number, got "two"`)
+}
+
+func TestLoadConfig_UnknownField(t *testing.T) {
+	f, err := ioutil.TempFile("", "config")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`{"hostname": "hooks.example.com", "listen": ":8080"}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = main.LoadConfig(f.Name(), env(nil))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown field "listen"`)
+}
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string {
+		return vars[key]
+	}
+}
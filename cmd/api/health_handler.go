@@ -0,0 +1,92 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/go-redis/redis"
+)
+
+// HealthCheck returns an error if the dependency is not ready to serve requests
+type HealthCheck func() error
+
+// HealthzHandler reports that the service is alive
+func HealthzHandler(w http.ResponseWriter, req *http.Request) {
+	fmt.Fprintln(w, "ok")
+}
+
+// ReadinessHandler reports whether the service is ready to accept deliveries, it
+// responds with 503 Service Unavailable listing failed checks if any.
+type ReadinessHandler struct {
+	checks map[string]HealthCheck
+}
+
+func NewReadinessHandler(checks map[string]HealthCheck) *ReadinessHandler {
+	return &ReadinessHandler{checks}
+}
+
+func (handler *ReadinessHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	names := make([]string, 0, len(handler.checks))
+	for name := range handler.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failed []string
+
+	for _, name := range names {
+		if err := handler.checks[name](); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		for _, msg := range failed {
+			fmt.Fprintln(w, msg)
+		}
+		return
+	}
+
+	fmt.Fprintln(w, "ok")
+}
+
+// redisHealthCheck checks whether redis is reachable
+func redisHealthCheck(redisClient *redis.Client) HealthCheck {
+	return func() error {
+		return redisClient.Ping().Err()
+	}
+}
+
+// urlHealthCheck checks whether downstream service URL is configured properly
+func urlHealthCheck(rawURL string) HealthCheck {
+	return func() error {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("expected absolute http(s) URL, got %q", rawURL)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	main "github.com/blamewarrior/hooks/cmd/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthzHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	main.HealthzHandler(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok\n", w.Body.String())
+}
+
+func TestReadinessHandler(t *testing.T) {
+	handler := main.NewReadinessHandler(map[string]main.HealthCheck{
+		"redis": func() error { return nil },
+		"web":   func() error { return nil },
+	})
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok\n", w.Body.String())
+}
+
+func TestReadinessHandler_FailedChecks(t *testing.T) {
+	handler := main.NewReadinessHandler(map[string]main.HealthCheck{
+		"web":   func() error { return errors.New(`expected absolute http(s) URL, got ""`) },
+		"redis": func() error { return errors.New("connection refused") },
+		"users": func() error { return nil },
+	})
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "redis: connection refused\nweb: expected absolute http(s) URL, got \"\"\n", w.Body.String())
+}
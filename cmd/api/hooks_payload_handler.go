@@ -16,26 +16,100 @@
 package main
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"time"
 
 	"github.com/blamewarrior/hooks"
+	"github.com/blamewarrior/hooks/github"
+	"github.com/blamewarrior/hooks/logging"
 )
 
 type HooksPayloadHandler struct {
 	mediator hooks.Mediator
+	secrets  hooks.Secrets
+	queue    hooks.Queue
 }
 
-func NewHooksPayloadHandler(mediator hooks.Mediator) *HooksPayloadHandler {
-	return &HooksPayloadHandler{mediator}
+func NewHooksPayloadHandler(mediator hooks.Mediator, secrets hooks.Secrets) *HooksPayloadHandler {
+	return &HooksPayloadHandler{mediator: mediator, secrets: secrets}
+}
+
+// NewAsyncHooksPayloadHandler returns handler that puts deliveries to the queue and
+// responds with 202 Accepted right away. Pings are still mediated synchronously
+// to report hook configuration errors back to GitHub.
+func NewAsyncHooksPayloadHandler(mediator hooks.Mediator, secrets hooks.Secrets, queue hooks.Queue) *HooksPayloadHandler {
+	return &HooksPayloadHandler{mediator: mediator, secrets: secrets, queue: queue}
 }
 
 func (handler *HooksPayloadHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if err := handler.handlePayload(w, req); err != nil {
+	start := time.Now()
+	event := req.Header.Get("X-GitHub-Event")
+
+	logger := logging.FromContext(req.Context()).WithFields(logging.Fields{
+		"event":       event,
+		"delivery_id": req.Header.Get("X-GitHub-Delivery"),
+		"repository":  repositoryFullName(req),
+	})
+
+	result := handler.serve(w, req.WithContext(logging.NewContext(req.Context(), logger)))
+
+	label := eventLabel(event, result)
+
+	receivedDeliveries.With(label, result).Inc()
+	deliveryDuration.With(label).Observe(time.Since(start).Seconds())
+}
+
+// eventLabel blanks event metrics label of forged deliveries, their X-GitHub-Event
+// header can be anything. Headers of deliveries signed by GitHub are kept as is.
+func eventLabel(event, result string) string {
+	if result == "forged" {
+		return ""
+	}
+
+	return event
+}
+
+// serve handles the delivery and returns the result of handling it for metrics
+func (handler *HooksPayloadHandler) serve(w http.ResponseWriter, req *http.Request) string {
+	err := handler.handlePayload(w, req)
+	logger := logging.FromContext(req.Context()).WithError(err)
+
+	if _, ok := err.(*unauthorizedError); ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		logger.With("status", http.StatusUnauthorized).Warnf("rejected forged delivery")
+		return "forged"
+	}
+
+	if _, ok := err.(*hooks.UnsupportedEventError); ok {
+		w.WriteHeader(http.StatusAccepted)
+		logger.With("status", http.StatusAccepted).Warnf("skipped unsupported event")
+		return "unsupported"
+	}
+
+	if _, ok := err.(*hooks.SavedForRetryError); ok {
+		w.WriteHeader(http.StatusAccepted)
+		logger.With("status", http.StatusAccepted).Warnf("saved delivery for retry")
+		return "saved_for_retry"
+	}
+
+	switch err {
+	case nil:
+		if handler.isQueued(req) {
+			logger.Debugf("queued delivery")
+			return "queued"
+		}
+		return "processed"
+	case hooks.ErrInvalidHook:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.With("status", http.StatusBadRequest).Warnf("rejected ping of misconfigured hook")
+		return "invalid_hook"
+	default:
 		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("%s\t%s\t%v\t%s", "POST", req.RequestURI, http.StatusInternalServerError, err)
+		logger.With("status", http.StatusInternalServerError).Errorf("failed to handle delivery")
+		return "failed"
 	}
 }
 
@@ -51,9 +125,96 @@ func (handler *HooksPayloadHandler) handlePayload(w http.ResponseWriter, req *ht
 		return err
 	}
 
+	if err := handler.verifySignature(req, respBytes); err != nil {
+		return err
+	}
+
 	event := req.Header.Get("X-GitHub-Event")
+	deliveryID := req.Header.Get("X-GitHub-Delivery")
+
+	if handler.isQueued(req) {
+		return handler.enqueue(w, req, event, deliveryID, respBytes)
+	}
+
+	err = handler.mediator.Mediate(req.Context(), event, deliveryID, respBytes)
 
-	err = handler.mediator.Mediate(event, respBytes)
+	if err == nil && event == "ping" {
+		// the body shows up in GitHub delivery log of the hook
+		fmt.Fprintf(w, "tracking of %s is confirmed\n", repositoryFullName(req))
+	}
 
 	return err
 }
+
+// isQueued reports whether delivery is put to the queue instead of being mediated
+func (handler *HooksPayloadHandler) isQueued(req *http.Request) bool {
+	return handler.queue != nil && req.Header.Get("X-GitHub-Event") != "ping"
+}
+
+func (handler *HooksPayloadHandler) enqueue(w http.ResponseWriter, req *http.Request, event, deliveryID string, payload []byte) error {
+	action, err := hooks.PayloadAction(payload)
+	if err != nil {
+		return err
+	}
+
+	// there is no point in queueing deliveries no one is going to handle
+	if !handler.mediator.Supports(event, action) {
+		return &hooks.UnsupportedEventError{Event: event, Action: action}
+	}
+
+	envelope := &hooks.Envelope{
+		Event:      event,
+		DeliveryID: deliveryID,
+		Repository: repositoryFullName(req),
+		Payload:    string(payload),
+		ReceivedAt: time.Now(),
+	}
+
+	if err := handler.queue.Push(envelope); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+
+	return nil
+}
+
+func (handler *HooksPayloadHandler) verifySignature(req *http.Request, payload []byte) error {
+	fullName := repositoryFullName(req)
+
+	secret, err := handler.secrets.Get(fullName)
+
+	if err == hooks.ErrNoSecret {
+		return &unauthorizedError{fullName, err}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = github.ValidateSignature(
+		payload,
+		secret,
+		req.Header.Get("X-Hub-Signature-256"),
+		req.Header.Get("X-Hub-Signature"),
+	)
+
+	if err != nil {
+		return &unauthorizedError{fullName, err}
+	}
+
+	return nil
+}
+
+func repositoryFullName(req *http.Request) string {
+	return fmt.Sprintf("%s/%s", req.URL.Query().Get(":username"), req.URL.Query().Get(":repo"))
+}
+
+type unauthorizedError struct {
+	repoFullName string
+	err          error
+}
+
+func (e *unauthorizedError) Error() string {
+	return fmt.Sprintf("rejected delivery for %s: %s", e.repoFullName, e.err)
+}
@@ -16,11 +16,18 @@
 package main_test
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/blamewarrior/hooks"
 	main "github.com/blamewarrior/hooks/cmd/api"
 
 	"github.com/stretchr/testify/assert"
@@ -32,19 +39,45 @@ type MediatorMock struct {
 	mock.Mock
 }
 
-func (m *MediatorMock) Mediate(event string, payload []byte) (err error) {
-	args := m.Called(event, payload)
+func (m *MediatorMock) Mediate(ctx context.Context, event, deliveryID string, payload []byte) (err error) {
+	args := m.Called(event, deliveryID, payload)
+	return args.Error(0)
+}
+
+func (m *MediatorMock) Supports(event, action string) bool {
+	return event != "commit_comment"
+}
+
+type SecretsMock struct {
+	mock.Mock
+}
+
+func (m *SecretsMock) Save(repoFullName, secret string) error {
+	args := m.Called(repoFullName, secret)
+	return args.Error(0)
+}
+
+func (m *SecretsMock) Get(repoFullName string) (string, error) {
+	args := m.Called(repoFullName)
+	return args.String(0), args.Error(1)
+}
+
+func (m *SecretsMock) Delete(repoFullName string) error {
+	args := m.Called(repoFullName)
 	return args.Error(0)
 }
 
 func TestHooksPayloadHandler(t *testing.T) {
 
-	payload := make([]byte, 0)
+	payload := []byte(`{"action":"opened"}`)
 
 	mediatorMock := new(MediatorMock)
-	mediatorMock.On("Mediate", "pull_request", payload).Return(nil)
+	mediatorMock.On("Mediate", "pull_request", "72d3162e-cc78-11e3-81ab-4c9367dc0958", payload).Return(nil)
+
+	secrets := new(SecretsMock)
+	secrets.On("Get", "blamewarrior_user/public-repo").Return("s3cr3t", nil)
 
-	handler := main.NewHooksPayloadHandler(mediatorMock)
+	handler := main.NewHooksPayloadHandler(mediatorMock, secrets)
 
 	req, err := http.NewRequest(
 		"POST",
@@ -55,15 +88,299 @@ func TestHooksPayloadHandler(t *testing.T) {
 	require.NoError(t, err)
 
 	req.Header.Add("X-GitHub-Event", "pull_request")
+	req.Header.Add("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	req.Header.Add("X-Hub-Signature-256", "sha256="+signPayload("s3cr3t", payload))
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+
+	mediatorMock.AssertExpectations(t)
+}
+
+type QueueMock struct {
+	mock.Mock
+}
 
-	http.DefaultClient.Do(req)
+func (m *QueueMock) Push(envelope *hooks.Envelope) error {
+	args := m.Called(envelope.Event, envelope.DeliveryID, envelope.Repository, envelope.Payload)
+	return args.Error(0)
+}
+
+func (m *QueueMock) Pop(timeout time.Duration) (*hooks.Envelope, error) {
+	args := m.Called(timeout)
+	return args.Get(0).(*hooks.Envelope), args.Error(1)
+}
+
+func (m *QueueMock) Ack(envelope *hooks.Envelope) error {
+	args := m.Called(envelope.DeliveryID)
+	return args.Error(0)
+}
+
+func (m *QueueMock) Requeue() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func TestHooksPayloadHandler_Async(t *testing.T) {
+	payload := []byte(`{"action":"opened"}`)
+
+	mediatorMock := new(MediatorMock)
+
+	queue := new(QueueMock)
+	queue.On("Push", "pull_request", "72d3162e-cc78-11e3-81ab-4c9367dc0958", "blamewarrior_user/public-repo", string(payload)).Return(nil)
+
+	secrets := new(SecretsMock)
+	secrets.On("Get", "blamewarrior_user/public-repo").Return("s3cr3t", nil)
+
+	handler := main.NewAsyncHooksPayloadHandler(mediatorMock, secrets, queue)
+
+	req, err := http.NewRequest(
+		"POST",
+		"/webhook?:username=blamewarrior_user&:repo=public-repo",
+		strings.NewReader(string(payload)),
+	)
 
 	require.NoError(t, err)
 
+	req.Header.Add("X-GitHub-Event", "pull_request")
+	req.Header.Add("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	req.Header.Add("X-Hub-Signature-256", "sha256="+signPayload("s3cr3t", payload))
+
 	w := httptest.NewRecorder()
 
 	handler.ServeHTTP(w, req)
 
-	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	queue.AssertExpectations(t)
+	mediatorMock.AssertNotCalled(t, "Mediate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHooksPayloadHandler_AsyncUnsupportedEvent(t *testing.T) {
+	payload := []byte(`{"action":"created"}`)
+
+	mediatorMock := new(MediatorMock)
+
+	queue := new(QueueMock)
+
+	secrets := new(SecretsMock)
+	secrets.On("Get", "blamewarrior_user/public-repo").Return("s3cr3t", nil)
+
+	handler := main.NewAsyncHooksPayloadHandler(mediatorMock, secrets, queue)
+
+	req, err := http.NewRequest(
+		"POST",
+		"/webhook?:username=blamewarrior_user&:repo=public-repo",
+		strings.NewReader(string(payload)),
+	)
+
+	require.NoError(t, err)
+
+	req.Header.Add("X-GitHub-Event", "commit_comment")
+	req.Header.Add("X-Hub-Signature-256", "sha256="+signPayload("s3cr3t", payload))
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	queue.AssertNotCalled(t, "Push", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHooksPayloadHandler_AsyncPing(t *testing.T) {
+	payload := []byte(`{"zen":"Keep it logically awesome.","hook_id":1}`)
+
+	mediatorMock := new(MediatorMock)
+	mediatorMock.On("Mediate", "ping", "", payload).Return(nil)
+
+	queue := new(QueueMock)
+
+	secrets := new(SecretsMock)
+	secrets.On("Get", "blamewarrior_user/public-repo").Return("s3cr3t", nil)
+
+	handler := main.NewAsyncHooksPayloadHandler(mediatorMock, secrets, queue)
+
+	req, err := http.NewRequest(
+		"POST",
+		"/webhook?:username=blamewarrior_user&:repo=public-repo",
+		strings.NewReader(string(payload)),
+	)
+
+	require.NoError(t, err)
+
+	req.Header.Add("X-GitHub-Event", "ping")
+	req.Header.Add("X-Hub-Signature-256", "sha256="+signPayload("s3cr3t", payload))
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mediatorMock.AssertExpectations(t)
+	queue.AssertNotCalled(t, "Push", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHooksPayloadHandler_SavedForRetry(t *testing.T) {
+	payload := []byte(`{"action":"opened"}`)
+
+	mediatorMock := new(MediatorMock)
+	mediatorMock.On("Mediate", "pull_request", "", payload).Return(&hooks.SavedForRetryError{Err: errors.New("unavailable")})
+
+	secrets := new(SecretsMock)
+	secrets.On("Get", "blamewarrior_user/public-repo").Return("s3cr3t", nil)
+
+	handler := main.NewHooksPayloadHandler(mediatorMock, secrets)
+
+	req, err := http.NewRequest(
+		"POST",
+		"/webhook?:username=blamewarrior_user&:repo=public-repo",
+		strings.NewReader(string(payload)),
+	)
+
+	require.NoError(t, err)
+
+	req.Header.Add("X-GitHub-Event", "pull_request")
+	req.Header.Add("X-Hub-Signature-256", "sha256="+signPayload("s3cr3t", payload))
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	// GitHub need not redeliver, the payload is retried by the retry worker
+	assert.Equal(t, http.StatusAccepted, w.Code)
+}
+
+func TestHooksPayloadHandler_Ping(t *testing.T) {
+	payload := []byte(`{"zen":"Keep it logically awesome.","hook_id":1}`)
+
+	results := []struct {
+		MediateErr error
+		Code       int
+		Body       string
+	}{
+		{nil, http.StatusOK, "tracking of blamewarrior_user/public-repo is confirmed\n"},
+		{hooks.ErrInvalidHook, http.StatusBadRequest, "invalid hook configuration\n"},
+	}
+
+	for _, result := range results {
+		mediatorMock := new(MediatorMock)
+		mediatorMock.On("Mediate", "ping", "", payload).Return(result.MediateErr)
+
+		secrets := new(SecretsMock)
+		secrets.On("Get", "blamewarrior_user/public-repo").Return("s3cr3t", nil)
+
+		handler := main.NewHooksPayloadHandler(mediatorMock, secrets)
+
+		req, err := http.NewRequest(
+			"POST",
+			"/webhook?:username=blamewarrior_user&:repo=public-repo",
+			strings.NewReader(string(payload)),
+		)
+
+		require.NoError(t, err)
+
+		req.Header.Add("X-GitHub-Event", "ping")
+		req.Header.Add("X-Hub-Signature-256", "sha256="+signPayload("s3cr3t", payload))
+
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, result.Code, w.Code)
+		assert.Equal(t, result.Body, w.Body.String())
+	}
+}
+
+func TestHooksPayloadHandler_UnsupportedEvent(t *testing.T) {
+	payload := []byte(`{"action":"created"}`)
+
+	mediatorMock := new(MediatorMock)
+	mediatorMock.On("Mediate", "commit_comment", "", payload).Return(&hooks.UnsupportedEventError{Event: "commit_comment", Action: "created"})
+
+	secrets := new(SecretsMock)
+	secrets.On("Get", "blamewarrior_user/public-repo").Return("s3cr3t", nil)
+
+	handler := main.NewHooksPayloadHandler(mediatorMock, secrets)
+
+	req, err := http.NewRequest(
+		"POST",
+		"/webhook?:username=blamewarrior_user&:repo=public-repo",
+		strings.NewReader(string(payload)),
+	)
+
+	require.NoError(t, err)
+
+	req.Header.Add("X-GitHub-Event", "commit_comment")
+	req.Header.Add("X-Hub-Signature-256", "sha256="+signPayload("s3cr3t", payload))
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	// unsupported events are counted per event
+	metricsReq, err := http.NewRequest("GET", "/metrics", nil)
+	require.NoError(t, err)
+
+	w = httptest.NewRecorder()
+
+	main.MetricsHandler().ServeHTTP(w, metricsReq)
+
+	assert.Regexp(t, `(?m)^hooks_deliveries_total\{event="commit_comment",result="unsupported"\} [1-9]`, w.Body.String())
+}
+
+func TestHooksPayloadHandler_ForgedPayload(t *testing.T) {
+	payload := []byte(`{"action":"opened"}`)
+
+	results := []struct {
+		Signature string
+		Secret    string
+		SecretErr error
+	}{
+		{Signature: "", Secret: "s3cr3t"},
+		{Signature: "sha256=" + signPayload("guessed", payload), Secret: "s3cr3t"},
+		{Signature: "sha256=" + signPayload("s3cr3t", payload), Secret: "", SecretErr: hooks.ErrNoSecret},
+	}
+
+	for _, result := range results {
+		mediatorMock := new(MediatorMock)
+
+		secrets := new(SecretsMock)
+		secrets.On("Get", "blamewarrior_user/public-repo").Return(result.Secret, result.SecretErr)
+
+		handler := main.NewHooksPayloadHandler(mediatorMock, secrets)
+
+		req, err := http.NewRequest(
+			"POST",
+			"/webhook?:username=blamewarrior_user&:repo=public-repo",
+			strings.NewReader(string(payload)),
+		)
+
+		require.NoError(t, err)
+
+		req.Header.Add("X-GitHub-Event", "pull_request")
+		if result.Signature != "" {
+			req.Header.Add("X-Hub-Signature-256", result.Signature)
+		}
+
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		mediatorMock.AssertNotCalled(t, "Mediate", mock.Anything, mock.Anything, mock.Anything)
+	}
+}
 
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
 }
@@ -16,59 +16,272 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/blamewarrior/hooks"
 	"github.com/blamewarrior/hooks/blamewarrior/collaborators"
 	"github.com/blamewarrior/hooks/blamewarrior/tokens"
 	"github.com/blamewarrior/hooks/blamewarrior/web"
 	"github.com/blamewarrior/hooks/github"
+	"github.com/blamewarrior/hooks/logging"
 	"github.com/bmizerany/pat"
 	"github.com/go-redis/redis"
 )
 
 func main() {
+	config, err := LoadConfig(os.Getenv("CONFIG_FILE"), os.Getenv)
+	if err != nil {
+		logging.Default.Fatalf("%s", err)
+	}
+
+	logLevel, _ := logging.ParseLevel(config.LogLevel)
+
+	// handlers, mediator and clients log with the logger carried by request
+	// context, falling back to the default one
+	logger := logging.New(os.Stderr, logLevel)
+	logging.Default = logger
+
 	mux := pat.New()
 
-	tokenClient := tokens.NewTokenClient()
+	githubAPI := config.GitHubAPI()
+	githubAPI.Transport = instrumentTransport("github")
 
-	repositories := github.NewGithubRepositories(tokenClient)
+	tokenClient := tokens.NewTokenClient()
+	tokenClient.BaseURL = config.Services.TokensURL
+	tokenClient.HTTPClient.Timeout = config.Timeouts.BlameWarrior.Duration
+	tokenClient.HTTPClient.Transport = instrumentTransport("tokens")
 
-	bwHost := os.Getenv("BW_HOST")
-	if bwHost == "" {
-		log.Fatal("missing bw host (expected to be passed via ENV['BW_HOST'])")
+	githubTokens, err := newGithubTokens(config, tokenClient, githubAPI)
+	if err != nil {
+		logger.Fatalf("%s", err)
 	}
 
-	opts := &redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", os.Getenv("REDIS_HOST"), os.Getenv("REDIS_PORT")),
-		Password: "", // no password set
-		DB:       0,  // use default DB
-	}
+	repositories := github.NewGithubRepositories(githubTokens)
+	repositories.APIConfig = githubAPI
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     config.Redis.Addr,
+		Password: config.Redis.Password,
+		DB:       config.Redis.DB,
+	})
 
-	redisClient := redis.NewClient(opts)
 	collaboratorsClient := collaborators.NewClient()
+	collaboratorsClient.BaseURL = config.Services.CollaboratorsURL
+	collaboratorsClient.HTTPClient.Timeout = config.Timeouts.BlameWarrior.Duration
+	collaboratorsClient.HTTPClient.Transport = instrumentTransport("collaborators")
 
-	mux.Post("/:action/:username/:repo", NewTrackingHandler(bwHost, repositories, redisClient, collaboratorsClient))
+	secrets := hooks.NewSecretsRepository(redisClient)
+	trackings := hooks.NewTrackingRepository(redisClient)
+
+	mux.Post("/:action/:username/:repo", NewTrackingHandler(config.Hostname, repositories, redisClient, collaboratorsClient, secrets, trackings))
 
 	payloadRepo := hooks.NewPayloadRepository(redisClient)
+	deliveries := hooks.NewDeliveryRepository(redisClient, config.DeliveriesWindow.Duration)
 
 	webClient := web.NewClient()
+	webClient.BaseURL = config.Services.WebURL
+	webClient.HTTPClient.Timeout = config.Timeouts.BlameWarrior.Duration
+	webClient.HTTPClient.Transport = instrumentTransport("web")
+
+	reviewersService := github.NewGithubReviewers(githubTokens)
+	reviewersService.APIConfig = githubAPI
+
+	blame := github.NewGithubBlame(githubTokens)
+	blame.APIConfig = githubAPI
+
+	reviewLoad := github.NewGithubReviewLoad(githubTokens)
+	reviewLoad.APIConfig = githubAPI
+
+	reviewerPicker, err := newReviewerPicker(config, blame, reviewLoad)
+	if err != nil {
+		logger.Fatalf("malformed reviewer strategies: %s", err)
+	}
+
+	codeOwners := github.NewGithubCodeOwners(githubTokens)
+	codeOwners.APIConfig = githubAPI
+
+	mediator := hooks.NewMediatorService(
+		payloadRepo, deliveries, trackings, webClient, collaboratorsClient, reviewersService,
+		hooks.NewCodeOwnersPicker(codeOwners, reviewerPicker), newPolicies(config),
+	)
+	mediator.ReviewerAssignments = reviewerAssignments
+	mediator.Use(hooks.LogEvents, hooks.MeasureEvents(mediatedEvents, mediationDuration))
+
+	// background workers mediate deliveries with workersCtx, it is cancelled once
+	// they run out of time to finish on shutdown
+	workersCtx, cancelWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	var queueWorker *hooks.QueueWorker
+
+	// deliveries are processed synchronously unless the number of async workers is set
+	if config.AsyncWorkers > 0 {
+		queue := hooks.NewQueueRepository(redisClient)
+		queueWorker = hooks.NewQueueWorker(queue, mediator, config.AsyncWorkers)
+
+		mux.Post("/:username/:repo/webhook", NewAsyncHooksPayloadHandler(mediator, secrets, queue))
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
 
-	reviewersService := github.NewGithubReviewers(tokenClient)
+			if err := queueWorker.Run(workersCtx); err != nil {
+				logger.Fatalf("failed to start queue worker: %s", err)
+			}
+		}()
+	} else {
+		mux.Post("/:username/:repo/webhook", NewHooksPayloadHandler(mediator, secrets))
+	}
+
+	retryWorker := hooks.NewRetryWorker(payloadRepo, mediator)
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		retryWorker.Run(workersCtx)
+	}()
+
+	registry.OnCollect(func() {
+		for _, list := range []string{"hooks", "dead_hooks", "fetch_collaborators", "queued_hooks", "processing_hooks"} {
+			n, err := redisClient.LLen(list).Result()
+			if err != nil {
+				logger.WithError(err).Warnf("failed to get length of %s list", list)
+				continue
+			}
 
-	mediator := hooks.NewMediatorService(payloadRepo, webClient, collaboratorsClient, reviewersService)
+			listLength.With(list).Set(float64(n))
+		}
+	})
 
-	mux.Post("/:username/:repo/webhook", NewHooksPayloadHandler(mediator))
+	mux.Get("/metrics", MetricsHandler())
+	mux.Get("/healthz", http.HandlerFunc(HealthzHandler))
+	mux.Get("/readyz", NewReadinessHandler(map[string]HealthCheck{
+		"redis":         redisHealthCheck(redisClient),
+		"web":           urlHealthCheck(webClient.BaseURL),
+		"users":         urlHealthCheck(tokenClient.BaseURL),
+		"collaborators": urlHealthCheck(collaboratorsClient.BaseURL),
+		"hooks":         urlHealthCheck(hooksBaseURL(config.Hostname)),
+	}))
 
 	http.Handle("/", mux)
 
-	log.Printf("blamewarrior users is running on 8080 port")
+	server := &http.Server{Addr: config.ListenAddr}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+
+		sig := <-signals
+		logger.Infof("received %s, shutting down", sig)
+
+		ctx, cancel := context.WithTimeout(context.Background(), config.Timeouts.Shutdown.Duration)
+		defer cancel()
 
-	err := http.ListenAndServe(":8080", nil)
+		// stop accepting deliveries and wait for the ones being mediated
+		if err := server.Shutdown(ctx); err != nil {
+			logger.WithError(err).Warnf("failed to wait for in-flight requests")
+		}
+
+		if queueWorker != nil {
+			if err := queueWorker.Shutdown(ctx); err != nil {
+				logger.WithError(err).Warnf("failed to wait for queued deliveries in flight")
+			}
+		}
+
+		// deliveries aborted here are saved for retry or requeued on the next start
+		cancelWorkers()
+		workers.Wait()
+	}()
+
+	logger.Infof("blamewarrior users is listening on %s", config.ListenAddr)
+
+	if err = server.ListenAndServe(); err != http.ErrServerClosed {
+		logger.Fatalf("failed to serve: %s", err)
+	}
+
+	<-stopped
+
+	logger.Infof("blamewarrior users has stopped")
+}
+
+// newGithubTokens returns client issuing tokens GitHub API requests are made with,
+// GitHub App installation tokens are used if the app is configured, otherwise
+// tokens of BlameWarrior users owning repositories.
+func newGithubTokens(config *Config, tokenClient tokens.Client, api github.APIConfig) (tokens.Client, error) {
+	if config.GitHub.AppID == 0 {
+		return tokenClient, nil
+	}
+
+	privateKey, err := ioutil.ReadFile(config.GitHub.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read GitHub App private key: %s", err)
+	}
+
+	app, err := github.NewGithubApp(config.GitHub.AppID, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	app.APIConfig = api
+	app.Installations = config.GitHub.Installations
+
+	return app, nil
+}
+
+// newReviewerPicker builds reviewer picker using configured strategy for all
+// repositories except those overriding it.
+func newReviewerPicker(config *Config, blame github.Blame, load github.ReviewLoad) (hooks.ReviewerPicker, error) {
+	defaultPicker, err := hooks.NewReviewerPicker(config.Reviewers.Strategy, rand.New(rand.NewSource(time.Now().UnixNano())), blame, load)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
+	}
+
+	pickers := make(map[string]hooks.ReviewerPicker)
+
+	for repoFullName, repo := range config.Repositories {
+		if repo.Strategy == "" {
+			continue
+		}
+
+		if pickers[repoFullName], err = hooks.NewReviewerPicker(repo.Strategy, rand.New(rand.NewSource(time.Now().UnixNano())), blame, load); err != nil {
+			return nil, err
+		}
+	}
+
+	return hooks.NewRepositoryPicker(defaultPicker, pickers), nil
+}
+
+// newPolicies builds reviewers assignment policies, repositories that override
+// neither required reviewers nor team use the default policy.
+func newPolicies(config *Config) *hooks.Policies {
+	defaultPolicy := hooks.Policy{RequiredReviewers: config.Reviewers.RequiredReviewers}
+
+	policies := make(map[string]hooks.Policy)
+
+	for repoFullName, repo := range config.Repositories {
+		if repo.RequiredReviewers == 0 && repo.Team == "" {
+			continue
+		}
+
+		policy := defaultPolicy
+		if repo.RequiredReviewers > 0 {
+			policy.RequiredReviewers = repo.RequiredReviewers
+		}
+		policy.Team = repo.Team
+
+		policies[repoFullName] = policy
 	}
+
+	return hooks.NewPolicies(defaultPolicy, policies)
 }
@@ -0,0 +1,115 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/blamewarrior/hooks/logging"
+	"github.com/blamewarrior/hooks/metrics"
+)
+
+var (
+	registry = metrics.NewRegistry()
+
+	receivedDeliveries = registry.Counter(
+		"hooks_deliveries_total",
+		"Webhook deliveries received by event and result of handling.",
+		"event", "result",
+	)
+	deliveryDuration = registry.Histogram(
+		"hooks_delivery_duration_seconds",
+		"Time it took to respond to webhook delivery.",
+		metrics.DefBuckets,
+		"event",
+	)
+	mediatedEvents = registry.Counter(
+		"hooks_mediated_events_total",
+		"Events mediated synchronously or by workers by event, action and result.",
+		"event", "action", "result",
+	)
+	mediationDuration = registry.Histogram(
+		"hooks_mediation_duration_seconds",
+		"Time it took to mediate event.",
+		metrics.DefBuckets,
+		"event", "action",
+	)
+	outboundRequests = registry.Counter(
+		"hooks_outbound_requests_total",
+		"Requests made to BlameWarrior services and GitHub API by client and response status code.",
+		"client", "code",
+	)
+	outboundDuration = registry.Histogram(
+		"hooks_outbound_request_duration_seconds",
+		"Time it took to get response from BlameWarrior services and GitHub API.",
+		metrics.DefBuckets,
+		"client",
+	)
+	listLength = registry.Gauge(
+		"hooks_list_length",
+		"Number of entries in redis lists of failed, dead and queued deliveries and failed collaborators fetches.",
+		"list",
+	)
+	reviewerAssignments = registry.Counter(
+		"hooks_reviewer_assignments_total",
+		"Reviewer assignments for pull requests by outcome.",
+		"outcome",
+	)
+)
+
+// MetricsHandler serves collected metrics in Prometheus text format.
+func MetricsHandler() http.Handler {
+	return registry
+}
+
+// instrumentedTransport counts requests made by client and observes their duration,
+// requests are logged with the logger carried by request context
+type instrumentedTransport struct {
+	client string
+	next   http.RoundTripper
+}
+
+func instrumentTransport(client string) http.RoundTripper {
+	return &instrumentedTransport{client, http.DefaultTransport}
+}
+
+func (transport *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	resp, err := transport.next.RoundTrip(req)
+
+	outboundDuration.With(transport.client).Observe(time.Since(start).Seconds())
+
+	logger := logging.FromContext(req.Context()).WithFields(logging.Fields{
+		"client":   transport.client,
+		"method":   req.Method,
+		"url":      req.URL.Host + req.URL.Path,
+		"duration": time.Since(start).Seconds(),
+	})
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+		logger.With("status", resp.StatusCode).Debugf("%s request to %s", req.Method, transport.client)
+	} else {
+		logger.WithError(err).Warnf("%s request to %s failed", req.Method, transport.client)
+	}
+	outboundRequests.With(transport.client, code).Inc()
+
+	return resp, err
+}
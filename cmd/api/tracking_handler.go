@@ -16,12 +16,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"net/http"
+	"strings"
 
+	"github.com/blamewarrior/hooks"
 	"github.com/blamewarrior/hooks/blamewarrior/collaborators"
 	"github.com/blamewarrior/hooks/github"
+	"github.com/blamewarrior/hooks/logging"
 	"github.com/go-redis/redis"
 )
 
@@ -32,6 +35,8 @@ type TrackingHandler struct {
 
 	repositories  github.Repositories
 	collaborators collaborators.Client
+	secrets       hooks.Secrets
+	trackings     hooks.Trackings
 }
 
 func (handler *TrackingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -43,20 +48,25 @@ func (handler *TrackingHandler) ServeHTTP(w http.ResponseWriter, req *http.Reque
 
 	trackingAction := req.URL.Query().Get(":action")
 
-	err := handler.DoAction(fullName, trackingAction)
+	logger := logging.FromContext(req.Context()).WithFields(logging.Fields{
+		"repository": fullName,
+		"action":     trackingAction,
+	})
+
+	err := handler.DoAction(logging.NewContext(req.Context(), logger), fullName, trackingAction)
 
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("%s\t%s\t%v\t%s", "POST", req.RequestURI, http.StatusInternalServerError, err)
+		logger.With("status", http.StatusInternalServerError).WithError(err).Errorf("failed to %s repository", trackingAction)
 	}
 
 }
 
-func (handler *TrackingHandler) DoAction(repoFullName, action string) (err error) {
+func (handler *TrackingHandler) DoAction(ctx context.Context, repoFullName, action string) (err error) {
 
 	switch action {
 	case "track":
-		err = handler.collaborators.FetchCollaborators(repoFullName)
+		err = handler.collaborators.FetchCollaborators(ctx, repoFullName)
 
 		if err != nil {
 			if err = handler.redisClient.LPush(
@@ -68,30 +78,74 @@ func (handler *TrackingHandler) DoAction(repoFullName, action string) (err error
 			return err
 		}
 
-		err = handler.repositories.Track(
-			github.Context{},
-			repoFullName,
-			fmt.Sprintf("https://%s/%s/webhook", handler.hostname, repoFullName),
-		)
-		return
+		var secret string
+
+		if secret, err = hooks.GenerateSecret(); err != nil {
+			return err
+		}
+
+		callbackURL := webhookURL(handler.hostname, repoFullName)
+
+		// the secret is only replaced once the hook is set up to sign deliveries
+		// with it, otherwise existing hook deliveries would be rejected
+		if err = handler.repositories.Track(github.Context{Context: ctx}, repoFullName, callbackURL, secret); err != nil {
+			return err
+		}
+
+		if err = handler.secrets.Save(repoFullName, secret); err != nil {
+			return err
+		}
+
+		// tracking is confirmed again once GitHub pings the hook, ping sent by
+		// GitHub on hook creation might have been rejected before the secret was saved
+		if err = handler.trackings.Delete(repoFullName); err != nil {
+			return err
+		}
+
+		return handler.repositories.Ping(github.Context{Context: ctx}, repoFullName, callbackURL)
 	case "untrack":
 		err = handler.repositories.Untrack(
-			github.Context{},
+			github.Context{Context: ctx},
 			repoFullName,
-			fmt.Sprintf("https://%s/%s/webhook", handler.hostname, repoFullName),
+			webhookURL(handler.hostname, repoFullName),
 		)
 
-		return
+		if err != nil {
+			return err
+		}
+
+		if err = handler.secrets.Delete(repoFullName); err != nil {
+			return err
+		}
+
+		return handler.trackings.Delete(repoFullName)
 	default:
 		return fmt.Errorf("Unsupported action %s", action)
 	}
 }
 
-func NewTrackingHandler(hostname string, repositories github.Repositories, redisClient *redis.Client, collaborators collaborators.Client) *TrackingHandler {
+// hooksBaseURL returns base URL GitHub delivers webhooks to. Hostname may
+// include scheme and port, e.g. to be reachable from GitHub Enterprise Server
+// inside private network, otherwise https is assumed.
+func hooksBaseURL(hostname string) string {
+	if strings.Contains(hostname, "://") {
+		return strings.TrimRight(hostname, "/")
+	}
+
+	return "https://" + hostname
+}
+
+func webhookURL(hostname, repoFullName string) string {
+	return fmt.Sprintf("%s/%s/webhook", hooksBaseURL(hostname), repoFullName)
+}
+
+func NewTrackingHandler(hostname string, repositories github.Repositories, redisClient *redis.Client, collaborators collaborators.Client, secrets hooks.Secrets, trackings hooks.Trackings) *TrackingHandler {
 	return &TrackingHandler{
 		hostname:      hostname,
 		repositories:  repositories,
 		redisClient:   redisClient,
 		collaborators: collaborators,
+		secrets:       secrets,
+		trackings:     trackings,
 	}
 }
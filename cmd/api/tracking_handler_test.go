@@ -16,9 +16,11 @@
 package main_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
+	"github.com/blamewarrior/hooks"
 	main "github.com/blamewarrior/hooks/cmd/api"
 	"github.com/blamewarrior/hooks/github"
 
@@ -30,9 +32,9 @@ type RepositoriesServiceMock struct {
 	mock.Mock
 }
 
-func (m *RepositoriesServiceMock) Track(ctx github.Context, repoFullName, callbackURL string) error {
+func (m *RepositoriesServiceMock) Track(ctx github.Context, repoFullName, callbackURL, secret string) error {
 
-	args := m.Called(ctx, repoFullName, callbackURL)
+	args := m.Called(ctx, repoFullName, callbackURL, secret)
 	return args.Error(0)
 
 }
@@ -44,24 +46,100 @@ func (m *RepositoriesServiceMock) Untrack(ctx github.Context, repoFullName, call
 
 }
 
+func (m *RepositoriesServiceMock) Ping(ctx github.Context, repoFullName, callbackURL string) error {
+
+	args := m.Called(ctx, repoFullName, callbackURL)
+	return args.Error(0)
+
+}
+
+type CollaboratorsClientMock struct {
+	mock.Mock
+}
+
+func (m *CollaboratorsClientMock) FetchCollaborators(ctx context.Context, repositoryFullName string) error {
+	args := m.Called(repositoryFullName)
+	return args.Error(0)
+}
+
+func (m *CollaboratorsClientMock) ListCollaborator(ctx context.Context, repositoryFullName string) ([]github.Collaborator, error) {
+	args := m.Called(repositoryFullName)
+	return args.Get(0).([]github.Collaborator), args.Error(1)
+}
+
+func (m *CollaboratorsClientMock) AddCollaborator(ctx context.Context, repositoryFullName string, collaborator *github.Collaborator) error {
+	args := m.Called(repositoryFullName, collaborator)
+	return args.Error(0)
+}
+
+func (m *CollaboratorsClientMock) EditCollaborator(ctx context.Context, repositoryFullName string, collaborator *github.Collaborator) error {
+	args := m.Called(repositoryFullName, collaborator)
+	return args.Error(0)
+}
+
+func (m *CollaboratorsClientMock) DeleteCollaborator(ctx context.Context, repositoryFullName, login string) error {
+	args := m.Called(repositoryFullName, login)
+	return args.Error(0)
+}
+
+type TrackingsMock struct {
+	mock.Mock
+}
+
+func (m *TrackingsMock) Confirm(repoFullName string, hookID int) error {
+	args := m.Called(repoFullName, hookID)
+	return args.Error(0)
+}
+
+func (m *TrackingsMock) Get(repoFullName string) (*hooks.Tracking, error) {
+	args := m.Called(repoFullName)
+
+	tracking, _ := args.Get(0).(*hooks.Tracking)
+
+	return tracking, args.Error(1)
+}
+
+func (m *TrackingsMock) Delete(repoFullName string) error {
+	args := m.Called(repoFullName)
+	return args.Error(0)
+}
+
 func TestTrackingHandler_DoAction(t *testing.T) {
 	reposService := new(RepositoriesServiceMock)
 
 	reposService.On(
 		"Track",
-		github.Context{},
+		github.Context{Context: context.Background()},
 		"blamewarrior/hooks",
 		"https://blamewarrior.com/blamewarrior/hooks/webhook",
+		mock.AnythingOfType("string"),
 	).Return(nil)
 
 	reposService.On(
 		"Untrack",
-		github.Context{},
+		github.Context{Context: context.Background()},
 		"blamewarrior/hooks",
 		"https://blamewarrior.com/blamewarrior/hooks/webhook",
 	).Return(nil)
 
-	handler := main.NewTrackingHandler("blamewarrior.com", reposService)
+	reposService.On(
+		"Ping",
+		github.Context{Context: context.Background()},
+		"blamewarrior/hooks",
+		"https://blamewarrior.com/blamewarrior/hooks/webhook",
+	).Return(nil)
+
+	collaboratorsClient := new(CollaboratorsClientMock)
+	collaboratorsClient.On("FetchCollaborators", "blamewarrior/hooks").Return(nil)
+
+	secrets := new(SecretsMock)
+	secrets.On("Save", "blamewarrior/hooks", mock.AnythingOfType("string")).Return(nil)
+	secrets.On("Delete", "blamewarrior/hooks").Return(nil)
+
+	trackings := new(TrackingsMock)
+	trackings.On("Delete", "blamewarrior/hooks").Return(nil)
+
+	handler := main.NewTrackingHandler("blamewarrior.com", reposService, nil, collaboratorsClient, secrets, trackings)
 
 	suits := []struct {
 		Action string
@@ -83,8 +161,59 @@ func TestTrackingHandler_DoAction(t *testing.T) {
 	}
 
 	for _, suits := range suits {
-		err := handler.DoAction("blamewarrior/hooks", suits.Action)
+		err := handler.DoAction(context.Background(), "blamewarrior/hooks", suits.Action)
 		assert.Equal(t, suits.Err, err)
 	}
 
+	secrets.AssertExpectations(t)
+	trackings.AssertNumberOfCalls(t, "Delete", 2)
+}
+
+func TestTrackingHandler_DoAction_TrackFailed(t *testing.T) {
+	reposService := new(RepositoriesServiceMock)
+	reposService.On(
+		"Track",
+		github.Context{Context: context.Background()},
+		"blamewarrior/hooks",
+		"https://blamewarrior.com/blamewarrior/hooks/webhook",
+		mock.AnythingOfType("string"),
+	).Return(errors.New("Validation Failed"))
+
+	collaboratorsClient := new(CollaboratorsClientMock)
+	collaboratorsClient.On("FetchCollaborators", "blamewarrior/hooks").Return(nil)
+
+	secrets := new(SecretsMock)
+	trackings := new(TrackingsMock)
+
+	handler := main.NewTrackingHandler("blamewarrior.com", reposService, nil, collaboratorsClient, secrets, trackings)
+
+	err := handler.DoAction(context.Background(), "blamewarrior/hooks", "track")
+	assert.EqualError(t, err, "Validation Failed")
+
+	// deliveries of the existing hook are still signed with the old secret
+	secrets.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	trackings.AssertNotCalled(t, "Delete", mock.Anything)
+	reposService.AssertNotCalled(t, "Ping", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTrackingHandler_DoAction_HostnameWithScheme(t *testing.T) {
+	reposService := new(RepositoriesServiceMock)
+	reposService.On(
+		"Untrack",
+		github.Context{Context: context.Background()},
+		"blamewarrior/hooks",
+		"http://hooks.internal:8080/blamewarrior/hooks/webhook",
+	).Return(nil)
+
+	secrets := new(SecretsMock)
+	secrets.On("Delete", "blamewarrior/hooks").Return(nil)
+
+	trackings := new(TrackingsMock)
+	trackings.On("Delete", "blamewarrior/hooks").Return(nil)
+
+	handler := main.NewTrackingHandler("http://hooks.internal:8080/", reposService, nil, nil, secrets, trackings)
+
+	assert.NoError(t, handler.DoAction(context.Background(), "blamewarrior/hooks", "untrack"))
+
+	reposService.AssertExpectations(t)
 }
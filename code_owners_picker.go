@@ -0,0 +1,84 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+import (
+	"context"
+	"strings"
+
+	bw "github.com/blamewarrior/hooks/blamewarrior"
+	gh "github.com/blamewarrior/hooks/github"
+	"github.com/blamewarrior/hooks/logging"
+)
+
+// CodeOwnersPicker prefers candidates who own files changed in pull request
+// according to repository CODEOWNERS. Both code owners and the rest of candidates
+// are picked by fallback picker, code owners go first. Candidates are picked by
+// fallback picker alone if code owners cannot be found out, e.g. when token lacks
+// access to repository contents or teams.
+type CodeOwnersPicker struct {
+	codeOwners gh.CodeOwners
+	fallback   ReviewerPicker
+}
+
+func NewCodeOwnersPicker(codeOwners gh.CodeOwners, fallback ReviewerPicker) *CodeOwnersPicker {
+	return &CodeOwnersPicker{codeOwners, fallback}
+}
+
+func (picker *CodeOwnersPicker) Pick(ctx context.Context, pullRequest *bw.PullRequest, candidates []gh.Collaborator, n int) ([]gh.Collaborator, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNoEligibleReviewer
+	}
+
+	logins, err := picker.codeOwners.Owners(gh.Context{Context: ctx}, pullRequest.RepositoryName, pullRequest.Number)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Warnf("failed to find code owners, picking reviewers without them")
+		return picker.fallback.Pick(ctx, pullRequest, candidates, n)
+	}
+
+	isOwner := make(map[string]bool, len(logins))
+	for _, login := range logins {
+		isOwner[strings.ToLower(login)] = true
+	}
+
+	owners := make([]gh.Collaborator, 0)
+	others := make([]gh.Collaborator, 0)
+
+	for _, candidate := range candidates {
+		if isOwner[strings.ToLower(candidate.Login)] {
+			owners = append(owners, candidate)
+		} else {
+			others = append(others, candidate)
+		}
+	}
+
+	picked := make([]gh.Collaborator, 0, n)
+
+	for _, group := range [][]gh.Collaborator{owners, others} {
+		if len(group) == 0 || len(picked) == n {
+			continue
+		}
+
+		groupPicked, err := picker.fallback.Pick(ctx, pullRequest, group, n-len(picked))
+		if err != nil {
+			return nil, err
+		}
+
+		picked = append(picked, groupPicked...)
+	}
+
+	return picked, nil
+}
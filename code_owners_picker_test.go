@@ -0,0 +1,85 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blamewarrior/hooks"
+	bw "github.com/blamewarrior/hooks/blamewarrior"
+	gh "github.com/blamewarrior/hooks/github"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeOwnersPicker_Pick(t *testing.T) {
+	pullRequest := &bw.PullRequest{RepositoryName: "blamewarrior/hooks", Number: 1347}
+
+	results := []struct {
+		Owners   []string
+		N        int
+		Expected []string
+	}{
+		{[]string{"Dave"}, 1, []string{"dave"}},
+		{[]string{"dave", "carol"}, 2, []string{"carol", "dave"}},
+		{[]string{"dave"}, 3, []string{"dave", "alice", "bob"}},
+		{[]string{"eve"}, 1, []string{"alice"}},
+		{nil, 2, []string{"alice", "bob"}},
+	}
+
+	for _, result := range results {
+		codeOwners := new(CodeOwnersMock)
+		codeOwners.On("Owners", mock.Anything, "blamewarrior/hooks", 1347).Return(result.Owners, nil)
+
+		picker := hooks.NewCodeOwnersPicker(codeOwners, hooks.NewRoundRobinPicker())
+
+		reviewers, err := picker.Pick(context.Background(), pullRequest, testCandidates, result.N)
+		require.NoError(t, err)
+
+		assert.Equal(t, result.Expected, logins(reviewers), "owners %v", result.Owners)
+	}
+}
+
+func TestCodeOwnersPicker_Pick_Error(t *testing.T) {
+	pullRequest := &bw.PullRequest{RepositoryName: "blamewarrior/hooks", Number: 1347}
+
+	codeOwners := new(CodeOwnersMock)
+	codeOwners.On("Owners", mock.Anything, "blamewarrior/hooks", 1347).Return(nil, errors.New("rate limit"))
+
+	picker := hooks.NewCodeOwnersPicker(codeOwners, hooks.NewRoundRobinPicker())
+
+	// reviewers are still picked, just not from code owners
+	reviewers, err := picker.Pick(context.Background(), pullRequest, testCandidates, 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"alice", "bob"}, logins(reviewers))
+}
+
+type CodeOwnersMock struct {
+	mock.Mock
+}
+
+func (m *CodeOwnersMock) Owners(ctx gh.Context, repoFullName string, pullNumber int) ([]string, error) {
+	args := m.Called(ctx, repoFullName, pullNumber)
+
+	owners, _ := args.Get(0).([]string)
+
+	return owners, args.Error(1)
+}
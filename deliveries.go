@@ -0,0 +1,152 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Deliveries keeps track of GitHub deliveries that have already been mediated
+// so that redelivered payloads are not processed twice.
+type Deliveries interface {
+	// Claim atomically marks delivery as being processed. It returns false if the
+	// delivery has already been processed or is being processed at the moment.
+	Claim(deliveryID string) (bool, error)
+	// Release forgets claimed delivery so that it can be processed again.
+	Release(deliveryID string) error
+	MarkProcessed(deliveryID string) error
+}
+
+const (
+	deliveryProcessing = "processing"
+	deliveryProcessed  = "processed"
+)
+
+// DefaultDeliveryLease is the time a claimed delivery is reserved for handler,
+// it must be longer than the handler may take. Claims of handlers that have
+// never finished, e.g. because the process has died, expire after the lease.
+const DefaultDeliveryLease = 5 * time.Minute
+
+type DeliveryRepository struct {
+	// Lease is the time a delivery is kept claimed unless it is processed or
+	// released
+	Lease time.Duration
+
+	redisClient *redis.Client
+	window      time.Duration
+}
+
+// NewDeliveryRepository returns Redis-backed deliveries store that remembers
+// processed deliveries for given window.
+func NewDeliveryRepository(redisClient *redis.Client, window time.Duration) *DeliveryRepository {
+	return &DeliveryRepository{
+		Lease:       DefaultDeliveryLease,
+		redisClient: redisClient,
+		window:      window,
+	}
+}
+
+func (repo *DeliveryRepository) Claim(deliveryID string) (bool, error) {
+	return repo.redisClient.SetNX(deliveryKey(deliveryID), deliveryProcessing, repo.Lease).Result()
+}
+
+func (repo *DeliveryRepository) Release(deliveryID string) error {
+	return repo.redisClient.Del(deliveryKey(deliveryID)).Err()
+}
+
+func (repo *DeliveryRepository) MarkProcessed(deliveryID string) error {
+	return repo.redisClient.Set(deliveryKey(deliveryID), deliveryProcessed, repo.window).Err()
+}
+
+func deliveryKey(deliveryID string) string {
+	return "deliveries:" + deliveryID
+}
+
+// MemoryDeliveries is an in-memory deliveries store intended for use in tests.
+type MemoryDeliveries struct {
+	// Lease is the time a delivery is kept claimed unless it is processed or
+	// released
+	Lease time.Duration
+
+	window time.Duration
+	now    func() time.Time
+
+	mu         sync.Mutex
+	deliveries map[string]memoryDelivery
+}
+
+type memoryDelivery struct {
+	State     string
+	ExpiresAt time.Time
+}
+
+func NewMemoryDeliveries(window time.Duration) *MemoryDeliveries {
+	return &MemoryDeliveries{
+		Lease:      DefaultDeliveryLease,
+		window:     window,
+		now:        time.Now,
+		deliveries: make(map[string]memoryDelivery),
+	}
+}
+
+func (deliveries *MemoryDeliveries) Claim(deliveryID string) (bool, error) {
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+
+	if _, ok := deliveries.lookup(deliveryID); ok {
+		return false, nil
+	}
+
+	deliveries.deliveries[deliveryID] = memoryDelivery{deliveryProcessing, deliveries.now().Add(deliveries.Lease)}
+
+	return true, nil
+}
+
+func (deliveries *MemoryDeliveries) Release(deliveryID string) error {
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+
+	delete(deliveries.deliveries, deliveryID)
+
+	return nil
+}
+
+func (deliveries *MemoryDeliveries) MarkProcessed(deliveryID string) error {
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+
+	deliveries.deliveries[deliveryID] = memoryDelivery{deliveryProcessed, deliveries.now().Add(deliveries.window)}
+
+	return nil
+}
+
+// lookup returns delivery unless it has expired, must be called with mu held
+func (deliveries *MemoryDeliveries) lookup(deliveryID string) (memoryDelivery, bool) {
+	delivery, ok := deliveries.deliveries[deliveryID]
+	if !ok {
+		return delivery, false
+	}
+
+	if !deliveries.now().Before(delivery.ExpiresAt) {
+		delete(deliveries.deliveries, deliveryID)
+		return delivery, false
+	}
+
+	return delivery, true
+}
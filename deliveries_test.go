@@ -0,0 +1,140 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blamewarrior/hooks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryRepository(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	deliveries := hooks.NewDeliveryRepository(redisClient, time.Hour)
+
+	err := deliveries.MarkProcessed("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+
+	claimed, err := deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	ttl, err := redisClient.TTL("deliveries:72d3162e-cc78-11e3-81ab-4c9367dc0958").Result()
+	require.NoError(t, err)
+	assert.True(t, ttl > hooks.DefaultDeliveryLease && ttl <= time.Hour)
+}
+
+func TestDeliveryRepository_Claim(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	deliveries := hooks.NewDeliveryRepository(redisClient, time.Hour)
+
+	claimed, err := deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	ttl, err := redisClient.TTL("deliveries:72d3162e-cc78-11e3-81ab-4c9367dc0958").Result()
+	require.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= hooks.DefaultDeliveryLease)
+
+	require.NoError(t, deliveries.Release("72d3162e-cc78-11e3-81ab-4c9367dc0958"))
+
+	claimed, err = deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	require.NoError(t, deliveries.MarkProcessed("72d3162e-cc78-11e3-81ab-4c9367dc0958"))
+
+	claimed, err = deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
+
+func TestDeliveryRepository_Claim_LeaseExpired(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	deliveries := hooks.NewDeliveryRepository(redisClient, time.Hour)
+	deliveries.Lease = 50 * time.Millisecond
+
+	claimed, err := deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	// the handler has died without releasing the claim
+	time.Sleep(60 * time.Millisecond)
+
+	claimed, err = deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestMemoryDeliveries(t *testing.T) {
+	deliveries := hooks.NewMemoryDeliveries(50 * time.Millisecond)
+
+	err := deliveries.MarkProcessed("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+
+	claimed, err := deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	time.Sleep(60 * time.Millisecond)
+
+	claimed, err = deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestMemoryDeliveries_Claim(t *testing.T) {
+	deliveries := hooks.NewMemoryDeliveries(time.Hour)
+	deliveries.Lease = 50 * time.Millisecond
+
+	claimed, err := deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	require.NoError(t, deliveries.Release("72d3162e-cc78-11e3-81ab-4c9367dc0958"))
+
+	claimed, err = deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	// the handler has died without releasing the claim
+	time.Sleep(60 * time.Millisecond)
+
+	claimed, err = deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
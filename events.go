@@ -0,0 +1,180 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/blamewarrior/hooks/logging"
+	"github.com/blamewarrior/hooks/metrics"
+)
+
+// Event is a GitHub webhook delivery being mediated.
+type Event struct {
+	Name       string
+	Action     string
+	DeliveryID string
+	Payload    []byte
+}
+
+// Handler handles GitHub events registered with MediatorService.
+type Handler interface {
+	Handle(ctx context.Context, event *Event) error
+}
+
+// HandlerFunc adapts ordinary function to Handler.
+type HandlerFunc func(ctx context.Context, event *Event) error
+
+func (fn HandlerFunc) Handle(ctx context.Context, event *Event) error {
+	return fn(ctx, event)
+}
+
+// Middleware wraps every registered handler, e.g. to log or deduplicate deliveries.
+type Middleware func(next Handler) Handler
+
+// UnsupportedEventError is returned for events and actions there is no handler
+// registered for.
+type UnsupportedEventError struct {
+	Event  string
+	Action string
+}
+
+func (e *UnsupportedEventError) Error() string {
+	if e.Action == "" {
+		return fmt.Sprintf("unsupported event %s", e.Event)
+	}
+
+	return fmt.Sprintf("unsupported %s event action %s", e.Event, e.Action)
+}
+
+// anyAction is the key handlers of all actions of event are registered with
+const anyAction = "*"
+
+// eventRegistry maps event and action pairs to their handlers. Events that carry
+// no action are registered with an empty one.
+type eventRegistry map[string]map[string]Handler
+
+func (registry eventRegistry) register(event, action string, handler Handler) {
+	if registry[event] == nil {
+		registry[event] = make(map[string]Handler)
+	}
+
+	registry[event][action] = handler
+}
+
+func (registry eventRegistry) lookup(event, action string) (Handler, error) {
+	if handler, ok := registry[event][action]; ok {
+		return handler, nil
+	}
+
+	if handler, ok := registry[event][anyAction]; ok {
+		return handler, nil
+	}
+
+	return nil, &UnsupportedEventError{event, action}
+}
+
+// PayloadAction returns the action of event payload, if any
+func PayloadAction(payload []byte) (string, error) {
+	var hook struct {
+		Action string `json:"action"`
+	}
+
+	if err := json.Unmarshal(payload, &hook); err != nil {
+		return "", err
+	}
+
+	return hook.Action, nil
+}
+
+// Deduplicate skips deliveries that have already been handled successfully or
+// are being handled at the moment, events with an empty delivery id are always
+// handled. The claim of delivery is released if its handling fails, so that it
+// can be retried.
+func Deduplicate(deliveries Deliveries) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, event *Event) error {
+			if event.DeliveryID == "" {
+				return next.Handle(ctx, event)
+			}
+
+			claimed, err := deliveries.Claim(event.DeliveryID)
+			if err != nil {
+				return err
+			}
+
+			if !claimed {
+				return nil
+			}
+
+			if err := next.Handle(ctx, event); err != nil {
+				if releaseErr := deliveries.Release(event.DeliveryID); releaseErr != nil {
+					logging.FromContext(ctx).WithError(releaseErr).Warnf("failed to release delivery %s", event.DeliveryID)
+				}
+
+				return err
+			}
+
+			return deliveries.MarkProcessed(event.DeliveryID)
+		})
+	}
+}
+
+// LogEvents logs every handled event along with the time it took to handle it
+// using the logger carried by ctx.
+func LogEvents(next Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, event *Event) error {
+		start := time.Now()
+
+		err := next.Handle(ctx, event)
+
+		logger := logging.FromContext(ctx).With("duration", time.Since(start).Seconds())
+		if err != nil {
+			logger.WithError(err).Errorf("failed to handle %s event", event.Name)
+		} else {
+			logger.Infof("handled %s event", event.Name)
+		}
+
+		return err
+	})
+}
+
+// MeasureEvents counts handled events by event, action and result, which is
+// either "ok" or "failed", and observes the time it took to handle them. The
+// counter must be partitioned by event, action and result labels, the histogram
+// by event and action.
+func MeasureEvents(handled *metrics.CounterVec, duration *metrics.HistogramVec) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, event *Event) error {
+			start := time.Now()
+
+			err := next.Handle(ctx, event)
+
+			duration.With(event.Name, event.Action).Observe(time.Since(start).Seconds())
+
+			result := "ok"
+			if err != nil {
+				result = "failed"
+			}
+			handled.With(event.Name, event.Action, result).Inc()
+
+			return err
+		})
+	}
+}
@@ -0,0 +1,194 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blamewarrior/hooks"
+	"github.com/blamewarrior/hooks/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMediatorService_Handle(t *testing.T) {
+	m := hooks.NewMediatorService(new(PayloadServiceMock), hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), new(WebClientMock), new(CollaboratorsClientMock), new(ReviewersServiceMock), hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+	var handled []string
+
+	m.Handle("commit_comment", hooks.HandlerFunc(func(ctx context.Context, event *hooks.Event) error {
+		handled = append(handled, "any:"+event.Action)
+		return nil
+	}))
+
+	m.HandleAction("commit_comment", "deleted", hooks.HandlerFunc(func(ctx context.Context, event *hooks.Event) error {
+		handled = append(handled, "deleted:"+event.Action)
+		return nil
+	}))
+
+	require.NoError(t, m.Mediate(context.Background(), "commit_comment", "", []byte(`{"action":"created"}`)))
+	require.NoError(t, m.Mediate(context.Background(), "commit_comment", "", []byte(`{"action":"deleted"}`)))
+
+	assert.Equal(t, []string{"any:created", "deleted:deleted"}, handled)
+}
+
+func TestMediatorService_Supports(t *testing.T) {
+	m := hooks.NewMediatorService(new(PayloadServiceMock), hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), new(WebClientMock), new(CollaboratorsClientMock), new(ReviewersServiceMock), hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+	assert.True(t, m.Supports("pull_request", "opened"))
+	assert.True(t, m.Supports("member", "added"))
+	assert.True(t, m.Supports("member", ""))
+	assert.True(t, m.Supports("ping", ""))
+
+	assert.False(t, m.Supports("member", "transferred"))
+	assert.False(t, m.Supports("commit_comment", ""))
+	assert.False(t, m.Supports("", ""))
+}
+
+func TestMediatorService_Use(t *testing.T) {
+	m := hooks.NewMediatorService(new(PayloadServiceMock), hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), new(WebClientMock), new(CollaboratorsClientMock), new(ReviewersServiceMock), hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+	var calls []string
+
+	middleware := func(name string) hooks.Middleware {
+		return func(next hooks.Handler) hooks.Handler {
+			return hooks.HandlerFunc(func(ctx context.Context, event *hooks.Event) error {
+				calls = append(calls, name)
+				return next.Handle(ctx, event)
+			})
+		}
+	}
+
+	m.Use(middleware("first"), middleware("second"))
+
+	m.Handle("push", hooks.HandlerFunc(func(ctx context.Context, event *hooks.Event) error {
+		calls = append(calls, "handler")
+		return nil
+	}))
+
+	require.NoError(t, m.Mediate(context.Background(), "push", "72d3162e-cc78-11e3-81ab-4c9367dc0958", []byte(`{"ref":"refs/heads/master"}`)))
+
+	// already processed deliveries never get to middlewares
+	require.NoError(t, m.Mediate(context.Background(), "push", "72d3162e-cc78-11e3-81ab-4c9367dc0958", []byte(`{"ref":"refs/heads/master"}`)))
+
+	assert.Equal(t, []string{"first", "second", "handler"}, calls)
+}
+
+func TestDeduplicate(t *testing.T) {
+	deliveries := hooks.NewMemoryDeliveries(time.Hour)
+
+	var calls int
+
+	failing := true
+
+	handler := hooks.Deduplicate(deliveries)(hooks.HandlerFunc(func(ctx context.Context, event *hooks.Event) error {
+		calls++
+
+		if failing {
+			return errors.New("web service is down")
+		}
+
+		return nil
+	}))
+
+	event := &hooks.Event{Name: "pull_request", Action: "opened", DeliveryID: "72d3162e-cc78-11e3-81ab-4c9367dc0958"}
+
+	assert.Error(t, handler.Handle(context.Background(), event))
+
+	failing = false
+
+	require.NoError(t, handler.Handle(context.Background(), event))
+	require.NoError(t, handler.Handle(context.Background(), event))
+
+	require.NoError(t, handler.Handle(context.Background(), &hooks.Event{Name: "pull_request", Action: "opened"}))
+	require.NoError(t, handler.Handle(context.Background(), &hooks.Event{Name: "pull_request", Action: "opened"}))
+
+	assert.Equal(t, 4, calls)
+}
+
+func TestDeduplicate_InFlight(t *testing.T) {
+	deliveries := hooks.NewMemoryDeliveries(time.Hour)
+
+	var (
+		calls   int32
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+
+	handler := hooks.Deduplicate(deliveries)(hooks.HandlerFunc(func(ctx context.Context, event *hooks.Event) error {
+		atomic.AddInt32(&calls, 1)
+
+		close(started)
+		<-release
+
+		return nil
+	}))
+
+	event := &hooks.Event{Name: "pull_request", Action: "opened", DeliveryID: "72d3162e-cc78-11e3-81ab-4c9367dc0958"}
+
+	done := make(chan error)
+	go func() {
+		done <- handler.Handle(context.Background(), event)
+	}()
+
+	<-started
+
+	// redelivered while the first delivery is being handled
+	require.NoError(t, handler.Handle(context.Background(), event))
+
+	close(release)
+	require.NoError(t, <-done)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestMeasureEvents(t *testing.T) {
+	registry := metrics.NewRegistry()
+	handled := registry.Counter("events_total", "Handled events.", "event", "action", "result")
+	duration := registry.Histogram("event_duration_seconds", "Event handling duration.", []float64{60}, "event", "action")
+
+	handler := hooks.MeasureEvents(handled, duration)(hooks.HandlerFunc(func(ctx context.Context, event *hooks.Event) error {
+		if event.Action == "closed" {
+			return errors.New("web service is down")
+		}
+
+		return nil
+	}))
+
+	handler.Handle(context.Background(), &hooks.Event{Name: "pull_request", Action: "opened"})
+	handler.Handle(context.Background(), &hooks.Event{Name: "pull_request", Action: "opened"})
+	handler.Handle(context.Background(), &hooks.Event{Name: "pull_request", Action: "closed"})
+	handler.Handle(context.Background(), &hooks.Event{Name: "ping"})
+
+	var buf bytes.Buffer
+	_, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+
+	output := buf.String()
+
+	assert.Contains(t, output, `events_total{event="ping",action="",result="ok"} 1`)
+	assert.Contains(t, output, `events_total{event="pull_request",action="closed",result="failed"} 1`)
+	assert.Contains(t, output, `events_total{event="pull_request",action="opened",result="ok"} 2`)
+	assert.NotContains(t, output, `events_total{event="pull_request",action="closed",result="ok"}`)
+	assert.Contains(t, output, `event_duration_seconds_count{event="pull_request",action="opened"} 2`)
+}
@@ -16,9 +16,12 @@ package github
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
 
@@ -38,20 +41,93 @@ type Collaborator struct {
 	Admin bool   `json:"admin"`
 }
 
+// Team is a GitHub team that can be requested to review pull request.
+type Team struct {
+	Id   int    `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
 type ReviewComment gh.PullRequestComment
 
 type GithubPullRequestHook struct {
+	Action      string         `json:"action"`
 	PullRequest gh.PullRequest `json:"pull_request"`
 
 	Repository struct {
 		FullName string `json:"full_name"`
 	} `json:"repository"`
 
-	RequestedReviewers []Collaborator `json:"requested_reviewers"`
+	// RequestedReviewers and RequestedTeams are sent by GitHub within pull_request object.
+	RequestedReviewers []Collaborator `json:"-"`
+	RequestedTeams     []Team         `json:"-"`
 
 	ReviewComments []ReviewComment `json:"review_comments"`
 }
 
+func (hook *GithubPullRequestHook) UnmarshalJSON(data []byte) error {
+	type pullRequestHook GithubPullRequestHook
+	if err := json.Unmarshal(data, (*pullRequestHook)(hook)); err != nil {
+		return err
+	}
+
+	var requested struct {
+		PullRequest struct {
+			RequestedReviewers []Collaborator `json:"requested_reviewers"`
+			RequestedTeams     []Team         `json:"requested_teams"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(data, &requested); err != nil {
+		return err
+	}
+
+	hook.RequestedReviewers = requested.PullRequest.RequestedReviewers
+	hook.RequestedTeams = requested.PullRequest.RequestedTeams
+
+	return nil
+}
+
+type GithubPullRequestReviewHook struct {
+	Action      string               `json:"action"`
+	Review      gh.PullRequestReview `json:"review"`
+	PullRequest gh.PullRequest       `json:"pull_request"`
+
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+type GithubPullRequestReviewCommentHook struct {
+	Action      string         `json:"action"`
+	Comment     ReviewComment  `json:"comment"`
+	PullRequest gh.PullRequest `json:"pull_request"`
+
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+type GithubIssueCommentHook struct {
+	Action  string          `json:"action"`
+	Issue   gh.Issue        `json:"issue"`
+	Comment gh.IssueComment `json:"comment"`
+
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// GithubPingHook is sent by GitHub right after webhook is created.
+type GithubPingHook struct {
+	Zen    string  `json:"zen"`
+	HookId int     `json:"hook_id"`
+	Hook   gh.Hook `json:"hook"`
+
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
 type GithubMemberHook struct {
 	Action string       `json:"action"`
 	Member Collaborator `json:"member"`
@@ -62,31 +138,148 @@ type GithubMemberHook struct {
 }
 
 // SplitRepositoryName splits full GitHub repository name into owner and name parts.
+// Names qualified with GitHub Enterprise host, e.g. github.example.com/owner/repo,
+// or given as repository URL are split the same way.
 func SplitRepositoryName(fullName string) (owner, repo string) {
-	sep := strings.IndexByte(fullName, '/')
-	if sep <= 0 || sep == len(fullName)-1 {
+	if u, err := url.Parse(fullName); err == nil && u.Host != "" {
+		fullName = u.Path
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(fullName, "/"), ".git"), "/")
+
+	switch len(parts) {
+	case 2:
+	case 3:
+		// host-qualified name
+		if !strings.ContainsAny(parts[0], ".:") {
+			return "", ""
+		}
+		parts = parts[1:]
+	default:
 		return "", ""
 	}
 
-	return fullName[0:sep], fullName[sep+1:]
+	if parts[0] == "" || parts[1] == "" {
+		return "", ""
+	}
+
+	return parts[0], parts[1]
+}
+
+// DefaultTimeout limits the time a single GitHub API request may take.
+const DefaultTimeout = 10 * time.Second
+
+// APIConfig configures how services reach GitHub API.
+type APIConfig struct {
+	// BaseURL is GitHub API endpoint, https://api.github.com/ if nil
+	BaseURL *url.URL
+	// UploadURL is GitHub uploads endpoint, https://uploads.github.com/ if nil
+	UploadURL *url.URL
+	Timeout   time.Duration
+	// Transport is used to make API requests, http.DefaultTransport if nil
+	Transport http.RoundTripper
 }
 
-func initAPIClient(ctx Context, tokenClient tokens.Client, owner string) (*gh.Client, error) {
+var DefaultAPIConfig = APIConfig{Timeout: DefaultTimeout}
 
-	token, err := tokenClient.GetToken(owner)
+// NewAPIConfig returns config for GitHub API at baseURL, which is either
+// https://api.github.com/ or GitHub Enterprise Server API endpoint. Enterprise
+// server can also be given by its hostname, e.g. https://github.example.com,
+// in which case /api/v3/ is appended. Empty uploadURL is derived from baseURL.
+func NewAPIConfig(baseURL, uploadURL string) (APIConfig, error) {
+	config := DefaultAPIConfig
+
+	base, err := parseEndpoint(baseURL)
+	if err != nil {
+		return config, fmt.Errorf("malformed GitHub API URL: %s", err)
+	}
+
+	if base.Host != "api.github.com" && base.Path == "/" {
+		base.Path = "/api/v3/"
+	}
+
+	var upload *url.URL
+
+	switch {
+	case uploadURL != "":
+		if upload, err = parseEndpoint(uploadURL); err != nil {
+			return config, fmt.Errorf("malformed GitHub upload URL: %s", err)
+		}
+	case base.Host == "api.github.com":
+		upload = &url.URL{Scheme: base.Scheme, Host: "uploads.github.com", Path: "/"}
+	default:
+		u := *base
+		u.Path = strings.TrimSuffix(u.Path, "/v3/") + "/uploads/"
+		upload = &u
+	}
+
+	config.BaseURL, config.UploadURL = base, upload
+
+	return config, nil
+}
+
+// parseEndpoint parses absolute URL making sure its path ends with a slash as
+// go-github requires
+func parseEndpoint(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("expected absolute http(s) URL, got %q", rawURL)
+	}
+
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	return u, nil
+}
+
+// RepositoryTokenClient is implemented by token clients that issue tokens per
+// repository rather than per its owner, e.g. GithubApp.
+type RepositoryTokenClient interface {
+	GetRepositoryToken(ctx context.Context, repoFullName string) (token string, err error)
+}
+
+func initAPIClient(ctx Context, tokenClient tokens.Client, repoFullName string, config APIConfig) (*gh.Client, error) {
+	var (
+		token string
+		err   error
+	)
+
+	if client, ok := tokenClient.(RepositoryTokenClient); ok {
+		token, err = client.GetRepositoryToken(ctx, repoFullName)
+	} else {
+		owner, _ := SplitRepositoryName(repoFullName)
+		token, err = tokenClient.GetToken(ctx, owner)
+	}
 
 	if err != nil {
 		return nil, fmt.Errorf("unable to get token to init API client: %s", err)
 	}
 
+	return newAPIClient(ctx, token, config), nil
+}
+
+// newAPIClient returns GitHub API client authenticating requests with given token
+func newAPIClient(ctx Context, token string, config APIConfig) *gh.Client {
 	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	oauthClient := oauth2.NewClient(ctx, tokenSource)
+	// oauth2 sends requests with the client passed in context
+	oauthClient := oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: config.Transport}), tokenSource)
+	oauthClient.Timeout = config.Timeout
 
 	api := gh.NewClient(oauthClient)
+	if config.BaseURL != nil {
+		api.BaseURL = config.BaseURL
+	}
+	if config.UploadURL != nil {
+		api.UploadURL = config.UploadURL
+	}
 	if ctx.BaseURL != nil {
 		api.BaseURL = ctx.BaseURL
 	}
 
-	return api, nil
-
+	return api
 }
@@ -0,0 +1,284 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	gh "github.com/google/go-github/github"
+)
+
+var ErrAppNotInstalled = errors.New("GitHub App is not installed")
+
+const (
+	// GitHub rejects app JWTs issued for longer than 10 minutes
+	appJWTLifetime = 9 * time.Minute
+	// appJWTClockSkew backdates JWTs in case our clock is ahead of GitHub's
+	appJWTClockSkew = time.Minute
+	// installation tokens are renewed this long before they expire
+	installationTokenMargin = time.Minute
+
+	mediaTypeAppsPreview = "application/vnd.github.machine-man-preview+json"
+)
+
+// GithubApp authenticates GitHub API requests as GitHub App installation. It issues
+// installation tokens in place of BlameWarrior users tokens, so services created
+// with it act as the app.
+type GithubApp struct {
+	APIConfig
+
+	// Installations maps repository full names or owner logins to app installation
+	// IDs, installations of other repositories are looked up via GitHub API
+	Installations map[string]int
+
+	id  int
+	key *rsa.PrivateKey
+
+	mu            sync.Mutex
+	installations map[string]int
+	tokens        map[int]installationToken
+}
+
+type installationToken struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// NewGithubApp returns GitHub App client for app with given ID, privateKeyPEM is
+// the app private key as downloaded from GitHub.
+func NewGithubApp(id int, privateKeyPEM []byte) (*GithubApp, error) {
+	key, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("malformed GitHub App private key: %s", err)
+	}
+
+	return &GithubApp{
+		APIConfig:     DefaultAPIConfig,
+		id:            id,
+		key:           key,
+		installations: make(map[string]int),
+		tokens:        make(map[int]installationToken),
+	}, nil
+}
+
+// GetRepositoryToken returns installation token to access repository with.
+func (app *GithubApp) GetRepositoryToken(ctx context.Context, repoFullName string) (string, error) {
+	owner, repo := SplitRepositoryName(repoFullName)
+	if owner == "" {
+		return "", fmt.Errorf("malformed repository name %q", repoFullName)
+	}
+
+	return app.token(ctx, owner+"/"+repo, owner, fmt.Sprintf("repos/%s/%s/installation", owner, repo))
+}
+
+// GetToken returns installation token for the organization or user account the app
+// is installed to.
+func (app *GithubApp) GetToken(ctx context.Context, owner string) (string, error) {
+	return app.token(ctx, owner, owner, fmt.Sprintf("orgs/%s/installation", owner), fmt.Sprintf("users/%s/installation", owner))
+}
+
+// token returns installation token for name, the cached installation ID is dropped
+// once GitHub reports it no longer exists, i.e. the app has been uninstalled
+func (app *GithubApp) token(ctx context.Context, name, owner string, paths ...string) (string, error) {
+	installationID, err := app.installationID(ctx, name, owner, paths)
+	if err != nil {
+		return "", fmt.Errorf("unable to find installation for %s: %s", name, err)
+	}
+
+	token, err := app.installationToken(ctx, installationID)
+	if err == ErrAppNotInstalled {
+		app.mu.Lock()
+		delete(app.tokens, installationID)
+		if app.installations[name] == installationID {
+			delete(app.installations, name)
+		}
+		app.mu.Unlock()
+
+		return "", fmt.Errorf("unable to create installation token for %s: %s", name, err)
+	}
+
+	return token, err
+}
+
+// installationID returns configured installation ID for name or its owner, otherwise
+// it is requested from GitHub and cached. Paths are tried in order until one of them
+// is found.
+func (app *GithubApp) installationID(ctx context.Context, name, owner string, paths []string) (int, error) {
+	if id, ok := app.Installations[name]; ok {
+		return id, nil
+	}
+
+	if id, ok := app.Installations[owner]; ok {
+		return id, nil
+	}
+
+	app.mu.Lock()
+	id, ok := app.installations[name]
+	app.mu.Unlock()
+
+	if ok {
+		return id, nil
+	}
+
+	var (
+		installation gh.Installation
+		err          = ErrAppNotInstalled
+	)
+	for _, path := range paths {
+		if err = app.do(ctx, "GET", path, &installation); err != ErrAppNotInstalled {
+			break
+		}
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if installation.GetID() == 0 {
+		return 0, errors.New("GitHub responded with no installation ID")
+	}
+
+	app.mu.Lock()
+	app.installations[name] = installation.GetID()
+	app.mu.Unlock()
+
+	return installation.GetID(), nil
+}
+
+// installationToken returns cached installation token unless it is about to expire,
+// otherwise exchanges app JWT for a new one
+func (app *GithubApp) installationToken(ctx context.Context, installationID int) (string, error) {
+	app.mu.Lock()
+	token, ok := app.tokens[installationID]
+	app.mu.Unlock()
+
+	if ok && time.Now().Add(installationTokenMargin).Before(token.ExpiresAt) {
+		return token.Token, nil
+	}
+
+	var resp gh.InstallationToken
+	if err := app.do(ctx, "POST", fmt.Sprintf("app/installations/%d/access_tokens", installationID), &resp); err != nil {
+		if err == ErrAppNotInstalled {
+			return "", err
+		}
+
+		return "", fmt.Errorf("unable to create installation token: %s", err)
+	}
+
+	if resp.GetToken() == "" {
+		return "", errors.New("GitHub responded with empty installation token")
+	}
+
+	token = installationToken{Token: resp.GetToken(), ExpiresAt: resp.GetExpiresAt()}
+
+	app.mu.Lock()
+	app.tokens[installationID] = token
+	app.mu.Unlock()
+
+	return token.Token, nil
+}
+
+// do makes GitHub API request authenticated as the app
+func (app *GithubApp) do(ctx context.Context, method, path string, v interface{}) error {
+	jwt, err := app.signJWT(time.Now())
+	if err != nil {
+		return err
+	}
+
+	api := newAPIClient(Context{Context: ctx}, jwt, app.APIConfig)
+
+	req, err := api.NewRequest(method, path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", mediaTypeAppsPreview)
+
+	if _, err = api.Do(ctx, req, v); err != nil {
+		if apiErr, ok := err.(*gh.ErrorResponse); ok && apiErr.Response.StatusCode == http.StatusNotFound {
+			return ErrAppNotInstalled
+		}
+
+		return fmt.Errorf("request failed: %s", err)
+	}
+
+	return nil
+}
+
+// signJWT returns RS256-signed JSON Web Token GitHub authenticates the app with
+func (app *GithubApp) signJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-appJWTClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.Itoa(app.id),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, app.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("unable to sign GitHub App JWT: %s", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parsePrivateKey parses PEM-encoded RSA key in either PKCS#1 format GitHub
+// issues keys in or PKCS#8
+func parsePrivateKey(privateKeyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("expected RSA key")
+	}
+
+	return rsaKey, nil
+}
@@ -0,0 +1,273 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package github_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blamewarrior/hooks/github"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGithubApp_GetRepositoryToken(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	key, keyPEM := generateAppKey(t)
+
+	var installationRequests, tokenRequests int
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/installation", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		assertAppJWT(t, &key.PublicKey, r)
+
+		installationRequests++
+		fmt.Fprint(w, `{"id":42}`)
+	})
+
+	mux.HandleFunc("/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		assertAppJWT(t, &key.PublicKey, r)
+
+		tokenRequests++
+		fmt.Fprintf(w, `{"token":"token-%d","expires_at":%q}`, tokenRequests, time.Now().Add(time.Hour).Format(time.RFC3339))
+	})
+
+	app, err := github.NewGithubApp(1, keyPEM)
+	require.NoError(t, err)
+	app.BaseURL = baseURL
+
+	for i := 0; i < 2; i++ {
+		token, err := app.GetRepositoryToken(context.Background(), "blamewarrior/hooks")
+		require.NoError(t, err)
+		assert.Equal(t, "token-1", token)
+	}
+
+	assert.Equal(t, 1, installationRequests)
+	assert.Equal(t, 1, tokenRequests)
+}
+
+func TestGithubApp_GetRepositoryToken_Expired(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	_, keyPEM := generateAppKey(t)
+
+	var tokenRequests int
+
+	mux.HandleFunc("/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		// too close to expiry to be reused
+		fmt.Fprintf(w, `{"token":"token-%d","expires_at":%q}`, tokenRequests, time.Now().Add(30*time.Second).Format(time.RFC3339))
+	})
+
+	app, err := github.NewGithubApp(1, keyPEM)
+	require.NoError(t, err)
+	app.BaseURL = baseURL
+	app.Installations = map[string]int{"blamewarrior": 42}
+
+	for i := 1; i <= 2; i++ {
+		token, err := app.GetRepositoryToken(context.Background(), "blamewarrior/hooks")
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("token-%d", i), token)
+	}
+}
+
+func TestGithubApp_GetRepositoryToken_NotInstalled(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	_, keyPEM := generateAppKey(t)
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/installation", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Not Found"}`)
+	})
+
+	app, err := github.NewGithubApp(1, keyPEM)
+	require.NoError(t, err)
+	app.BaseURL = baseURL
+
+	_, err = app.GetRepositoryToken(context.Background(), "blamewarrior/hooks")
+	assert.EqualError(t, err, "unable to find installation for blamewarrior/hooks: GitHub App is not installed")
+}
+
+func TestGithubApp_GetRepositoryToken_Uninstalled(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	_, keyPEM := generateAppKey(t)
+
+	var installationRequests, tokenRequests int
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/installation", func(w http.ResponseWriter, r *http.Request) {
+		installationRequests++
+		fmt.Fprintf(w, `{"id":%d}`, 41+installationRequests)
+	})
+
+	mux.HandleFunc("/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		if tokenRequests > 1 {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+
+			return
+		}
+
+		fmt.Fprintf(w, `{"token":"token-42","expires_at":%q}`, time.Now().Add(30*time.Second).Format(time.RFC3339))
+	})
+
+	mux.HandleFunc("/app/installations/43/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"token":"token-43","expires_at":%q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	})
+
+	app, err := github.NewGithubApp(1, keyPEM)
+	require.NoError(t, err)
+	app.BaseURL = baseURL
+
+	token, err := app.GetRepositoryToken(context.Background(), "blamewarrior/hooks")
+	require.NoError(t, err)
+	assert.Equal(t, "token-42", token)
+
+	_, err = app.GetRepositoryToken(context.Background(), "blamewarrior/hooks")
+	assert.EqualError(t, err, "unable to create installation token for blamewarrior/hooks: GitHub App is not installed")
+
+	// the app has been installed again
+	token, err = app.GetRepositoryToken(context.Background(), "blamewarrior/hooks")
+	require.NoError(t, err)
+	assert.Equal(t, "token-43", token)
+
+	assert.Equal(t, 2, installationRequests)
+}
+
+func TestGithubApp_GetToken(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	_, keyPEM := generateAppKey(t)
+
+	mux.HandleFunc("/orgs/blamewarrior/installation", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":42}`)
+	})
+
+	mux.HandleFunc("/orgs/octocat/installation", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Not Found"}`)
+	})
+
+	mux.HandleFunc("/users/octocat/installation", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":43}`)
+	})
+
+	mux.HandleFunc("/app/installations/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/app/installations/"), "/access_tokens")
+		fmt.Fprintf(w, `{"token":"token-%s","expires_at":%q}`, id, time.Now().Add(time.Hour).Format(time.RFC3339))
+	})
+
+	app, err := github.NewGithubApp(1, keyPEM)
+	require.NoError(t, err)
+	app.BaseURL = baseURL
+
+	token, err := app.GetToken(context.Background(), "blamewarrior")
+	require.NoError(t, err)
+	assert.Equal(t, "token-42", token)
+
+	token, err = app.GetToken(context.Background(), "octocat")
+	require.NoError(t, err)
+	assert.Equal(t, "token-43", token)
+}
+
+func TestGithubApp_Repositories(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	_, keyPEM := generateAppKey(t)
+
+	mux.HandleFunc("/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"token":"installation-token","expires_at":%q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/hooks", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer installation-token", r.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"id":1}`)
+	})
+
+	app, err := github.NewGithubApp(1, keyPEM)
+	require.NoError(t, err)
+	app.BaseURL = baseURL
+	app.Installations = map[string]int{"blamewarrior/hooks": 42}
+
+	githubRepos := github.NewGithubRepositories(app)
+	githubRepos.BaseURL = baseURL
+
+	err = githubRepos.Track(github.Context{Context: context.Background()}, "blamewarrior/hooks", "https://example.com/blamewarrior/hooks/webhook", "s3cr3t")
+	require.NoError(t, err)
+}
+
+func TestNewGithubApp_MalformedKey(t *testing.T) {
+	_, err := github.NewGithubApp(1, []byte("not a key"))
+	assert.EqualError(t, err, "malformed GitHub App private key: no PEM data found")
+}
+
+func generateAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func assertAppJWT(t *testing.T, key *rsa.PublicKey, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	require.True(t, strings.HasPrefix(auth, "Bearer "), auth)
+
+	parts := strings.Split(strings.TrimPrefix(auth, "Bearer "), ".")
+	require.Len(t, parts, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	require.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature))
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+
+	var claims struct {
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+		Issuer    string `json:"iss"`
+	}
+	require.NoError(t, json.Unmarshal(b, &claims))
+
+	assert.Equal(t, "1", claims.Issuer)
+	assert.True(t, claims.IssuedAt <= time.Now().Unix())
+	assert.True(t, claims.ExpiresAt-claims.IssuedAt <= int64(10*time.Minute/time.Second))
+}
@@ -0,0 +1,441 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package github
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blamewarrior/hooks/blamewarrior/tokens"
+	gh "github.com/google/go-github/github"
+)
+
+const (
+	// DefaultBlameDepth is the number of latest commits per file walked back to
+	// compute authorship of touched lines.
+	DefaultBlameDepth = 30
+	// DefaultBlameMaxFiles is the number of changed files with most touched lines
+	// blamed per pull request.
+	DefaultBlameMaxFiles = 10
+	// DefaultBlameMaxRequests is the number of GitHub API requests made to walk back
+	// the history of files changed by pull request.
+	DefaultBlameMaxRequests = 50
+)
+
+// errBlameLimitExceeded is returned once blame has made MaxRequests API requests
+var errBlameLimitExceeded = errors.New("blame requests limit exceeded")
+
+type Blame interface {
+	Authorship(ctx Context, repoFullName string, pullNumber int) (map[string]int, error)
+}
+
+type GithubBlame struct {
+	APIConfig
+	Depth int
+	// MaxFiles and MaxRequests bound the number of files and GitHub API requests
+	// spent on a single pull request, zero means no limit
+	MaxFiles    int
+	MaxRequests int
+
+	tokenClient tokens.Client
+}
+
+func NewGithubBlame(tokenClient tokens.Client) *GithubBlame {
+	return &GithubBlame{
+		APIConfig:   DefaultAPIConfig,
+		Depth:       DefaultBlameDepth,
+		MaxFiles:    DefaultBlameMaxFiles,
+		MaxRequests: DefaultBlameMaxRequests,
+		tokenClient: tokenClient,
+	}
+}
+
+// Authorship returns the number of lines touched by pull request per login of their
+// author. Authorship is computed by walking back the history of each changed file
+// on the base branch, lines older than Depth commits are not attributed. Only MaxFiles
+// files with most touched lines are blamed, once MaxRequests requests are made the
+// authorship computed so far is returned.
+func (service *GithubBlame) Authorship(ctx Context, repoFullName string, pullNumber int) (map[string]int, error) {
+	owner, repo := SplitRepositoryName(repoFullName)
+
+	api, err := initAPIClient(ctx, service.tokenClient, repoFullName, service.APIConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	pullRequest, _, err := api.PullRequests.Get(ctx, owner, repo, pullNumber)
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	files, err := listPullRequestFiles(ctx, api, owner, repo, pullNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	touched := make([]touchedFile, 0, len(files))
+	for _, file := range files {
+		if file.GetStatus() == "added" {
+			continue
+		}
+
+		lines := make(map[int]bool)
+		for _, lineRange := range TouchedRanges(ParseDiffHunks(file.GetPatch())) {
+			for line := lineRange.Start; line <= lineRange.End; line++ {
+				lines[line] = true
+			}
+		}
+
+		if len(lines) == 0 {
+			continue
+		}
+
+		touched = append(touched, touchedFile{file.GetFilename(), lines})
+	}
+
+	sort.SliceStable(touched, func(i, j int) bool {
+		return len(touched[i].Lines) > len(touched[j].Lines)
+	})
+
+	if service.MaxFiles > 0 && len(touched) > service.MaxFiles {
+		touched = touched[:service.MaxFiles]
+	}
+
+	blame := &fileBlame{
+		ctx:        ctx,
+		api:        api,
+		owner:      owner,
+		repo:       repo,
+		depth:      service.Depth,
+		requests:   service.MaxRequests,
+		commits:    make(map[string]*gh.RepositoryCommit),
+		authorship: make(map[string]int),
+	}
+
+	if blame.requests <= 0 {
+		blame.requests = -1
+	}
+
+	for _, file := range touched {
+		err := blame.walk(pullRequest.GetBase().GetSHA(), file.Path, file.Lines)
+		if err == errBlameLimitExceeded {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return blame.authorship, nil
+}
+
+type touchedFile struct {
+	Path  string
+	Lines map[int]bool
+}
+
+type fileBlame struct {
+	ctx         Context
+	api         *gh.Client
+	owner, repo string
+	depth       int
+	// requests is the number of API requests left, negative if unlimited
+	requests int
+
+	commits    map[string]*gh.RepositoryCommit
+	authorship map[string]int
+}
+
+// walk attributes lines of file at sha to authors of commits that added them
+func (blame *fileBlame) walk(sha, path string, lines map[int]bool) error {
+	if err := blame.request(); err != nil {
+		return err
+	}
+
+	opt := &gh.CommitsListOptions{SHA: sha, Path: path, ListOptions: gh.ListOptions{PerPage: blame.depth}}
+
+	history, _, err := blame.api.Repositories.ListCommits(blame.ctx, blame.owner, blame.repo, opt)
+	if err != nil {
+		return apiError(err)
+	}
+
+	for _, entry := range history {
+		// merge commits are diffed against the first parent only, the lines they
+		// seem to add are attributed to commits of merged branch instead
+		if len(lines) == 0 || len(entry.Parents) > 1 {
+			continue
+		}
+
+		commit, err := blame.commit(entry.GetSHA())
+		if err != nil {
+			return err
+		}
+
+		file := findCommitFile(commit, path)
+		if file == nil {
+			continue
+		}
+
+		login := entry.GetAuthor().GetLogin()
+
+		if file.GetStatus() == "added" {
+			if login != "" {
+				blame.authorship[login] += len(lines)
+			}
+
+			return nil
+		}
+
+		hunks := ParseDiffHunks(file.GetPatch())
+
+		previous := make(map[int]bool, len(lines))
+		for line := range lines {
+			if previousLine, ok := hunks.PreviousLine(line); ok {
+				previous[previousLine] = true
+			} else if login != "" {
+				blame.authorship[login]++
+			}
+		}
+
+		lines = previous
+	}
+
+	return nil
+}
+
+func (blame *fileBlame) commit(sha string) (*gh.RepositoryCommit, error) {
+	if commit, ok := blame.commits[sha]; ok {
+		return commit, nil
+	}
+
+	if err := blame.request(); err != nil {
+		return nil, err
+	}
+
+	commit, _, err := blame.api.Repositories.GetCommit(blame.ctx, blame.owner, blame.repo, sha)
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	blame.commits[sha] = commit
+
+	return commit, nil
+}
+
+// request accounts for an API request, it fails once no requests are left
+func (blame *fileBlame) request() error {
+	if blame.requests < 0 {
+		return nil
+	}
+
+	if blame.requests == 0 {
+		return errBlameLimitExceeded
+	}
+
+	blame.requests--
+
+	return nil
+}
+
+func findCommitFile(commit *gh.RepositoryCommit, path string) *gh.CommitFile {
+	for i := range commit.Files {
+		if commit.Files[i].GetFilename() == path {
+			return &commit.Files[i]
+		}
+	}
+
+	return nil
+}
+
+// LineRange is an inclusive range of line numbers.
+type LineRange struct {
+	Start, End int
+}
+
+// DiffHunk is a single hunk of unified diff.
+type DiffHunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []string
+}
+
+type DiffHunks []DiffHunk
+
+// ParseDiffHunks parses the patch of a file as returned by GitHub API, malformed
+// hunks are skipped.
+func ParseDiffHunks(patch string) DiffHunks {
+	hunks := make(DiffHunks, 0)
+
+	var current *DiffHunk
+
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			hunk, err := parseHunkHeader(line)
+			if err != nil {
+				current = nil
+				continue
+			}
+
+			hunks = append(hunks, hunk)
+			current = &hunks[len(hunks)-1]
+
+			continue
+		}
+
+		if current == nil || line == "" || line[0] == '\\' {
+			continue
+		}
+
+		current.Lines = append(current.Lines, line)
+	}
+
+	return hunks
+}
+
+// parseHunkHeader parses "@@ -oldStart,oldLines +newStart,newLines @@" line
+func parseHunkHeader(header string) (hunk DiffHunk, err error) {
+	fields := strings.Fields(header)
+	if len(fields) < 4 || !strings.HasPrefix(fields[3], "@@") {
+		return hunk, fmt.Errorf("malformed hunk header %q", header)
+	}
+
+	if hunk.OldStart, hunk.OldLines, err = parseHunkRange(fields[1], "-"); err != nil {
+		return hunk, err
+	}
+
+	if hunk.NewStart, hunk.NewLines, err = parseHunkRange(fields[2], "+"); err != nil {
+		return hunk, err
+	}
+
+	return hunk, nil
+}
+
+func parseHunkRange(s, prefix string) (start, lines int, err error) {
+	if !strings.HasPrefix(s, prefix) {
+		return 0, 0, fmt.Errorf("malformed hunk range %q", s)
+	}
+
+	parts := strings.SplitN(s[1:], ",", 2)
+
+	if start, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk range %q", s)
+	}
+
+	lines = 1
+	if len(parts) == 2 {
+		if lines, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, fmt.Errorf("malformed hunk range %q", s)
+		}
+	}
+
+	return start, lines, nil
+}
+
+// TouchedRanges returns ranges of lines of the original file that are changed or
+// removed by hunks. For hunks that only add lines the surrounding context lines
+// are considered touched.
+func TouchedRanges(hunks DiffHunks) []LineRange {
+	touched := make([]int, 0)
+
+	for _, hunk := range hunks {
+		removed := make([]int, 0)
+		context := make([]int, 0)
+
+		oldLine := hunk.OldStart
+		for _, line := range hunk.Lines {
+			switch line[0] {
+			case '+':
+			case '-':
+				removed = append(removed, oldLine)
+				oldLine++
+			default:
+				context = append(context, oldLine)
+				oldLine++
+			}
+		}
+
+		if len(removed) > 0 {
+			touched = append(touched, removed...)
+		} else {
+			touched = append(touched, context...)
+		}
+	}
+
+	sort.Ints(touched)
+
+	ranges := make([]LineRange, 0)
+	for _, line := range touched {
+		if n := len(ranges); n > 0 && ranges[n-1].End+1 >= line {
+			if line > ranges[n-1].End {
+				ranges[n-1].End = line
+			}
+
+			continue
+		}
+
+		ranges = append(ranges, LineRange{line, line})
+	}
+
+	return ranges
+}
+
+// PreviousLine maps line number of the file after hunks were applied to the line
+// number before. It returns false if the line was added by hunks.
+func (hunks DiffHunks) PreviousLine(line int) (int, bool) {
+	delta := 0
+
+	for _, hunk := range hunks {
+		start := hunk.NewStart
+		if hunk.NewLines == 0 {
+			// for hunks removing lines only NewStart points to the line preceding them
+			start++
+		}
+
+		if line < start {
+			return line - delta, true
+		}
+
+		if line < start+hunk.NewLines {
+			oldLine, newLine := hunk.OldStart, hunk.NewStart
+
+			for _, hunkLine := range hunk.Lines {
+				switch hunkLine[0] {
+				case '+':
+					if newLine == line {
+						return 0, false
+					}
+					newLine++
+				case '-':
+					oldLine++
+				default:
+					if newLine == line {
+						return oldLine, true
+					}
+					oldLine++
+					newLine++
+				}
+			}
+		}
+
+		delta += hunk.NewLines - hunk.OldLines
+	}
+
+	return line - delta, true
+}
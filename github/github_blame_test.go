@@ -0,0 +1,246 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package github_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/blamewarrior/hooks/github"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDiffHunks(t *testing.T) {
+	hunks := github.ParseDiffHunks(testPatch)
+
+	require.Len(t, hunks, 2)
+
+	assert.Equal(t, github.DiffHunk{
+		OldStart: 1, OldLines: 4,
+		NewStart: 1, NewLines: 3,
+		Lines: []string{" line1", "-line2", "-line3", "+line23", " line4"},
+	}, hunks[0])
+
+	assert.Equal(t, github.DiffHunk{
+		OldStart: 10, OldLines: 2,
+		NewStart: 9, NewLines: 4,
+		Lines: []string{" line10", "+line10.1", "+line10.2", " line11"},
+	}, hunks[1])
+}
+
+func TestTouchedRanges(t *testing.T) {
+	ranges := github.TouchedRanges(github.ParseDiffHunks(testPatch))
+
+	assert.Equal(t, []github.LineRange{{2, 3}, {10, 11}}, ranges)
+}
+
+func TestDiffHunks_PreviousLine(t *testing.T) {
+	hunks := github.ParseDiffHunks(testPatch)
+
+	results := []struct {
+		Line         int
+		PreviousLine int
+		Existed      bool
+	}{
+		{1, 1, true},
+		{2, 0, false},
+		{3, 4, true},
+		{5, 6, true},
+		{9, 10, true},
+		{10, 0, false},
+		{11, 0, false},
+		{12, 11, true},
+		{20, 19, true},
+	}
+
+	for _, result := range results {
+		previousLine, existed := hunks.PreviousLine(result.Line)
+
+		assert.Equal(t, result.Existed, existed, "line %d", result.Line)
+		assert.Equal(t, result.PreviousLine, previousLine, "line %d", result.Line)
+	}
+}
+
+func TestGithubBlame_Authorship(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/pulls/1347", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{"number": 1347, "base": map[string]string{"sha": "base"}})
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/pulls/1347/files", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, []map[string]string{
+			{"filename": "main.go", "status": "modified", "patch": "@@ -1,4 +1,3 @@\n line1\n-line2\n-line3\n+line23\n line4"},
+			{"filename": "README.md", "status": "added", "patch": "@@ -0,0 +1 @@\n+# Hooks"},
+		})
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/commits", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "base", r.URL.Query().Get("sha"))
+		assert.Equal(t, "main.go", r.URL.Query().Get("path"))
+
+		writeJSON(t, w, []map[string]interface{}{
+			{"sha": "c3", "author": map[string]string{"login": "carol"}, "parents": []map[string]string{{"sha": "c2"}, {"sha": "b1"}}},
+			{"sha": "c2", "author": map[string]string{"login": "bob"}, "parents": []map[string]string{{"sha": "c1"}}},
+			{"sha": "c1", "author": map[string]string{"login": "alice"}},
+		})
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/commits/c2", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{
+			"sha":   "c2",
+			"files": []map[string]string{{"filename": "main.go", "status": "modified", "patch": "@@ -1,2 +1,3 @@\n line1\n+line2\n line3"}},
+		})
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/commits/c1", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{
+			"sha":   "c1",
+			"files": []map[string]string{{"filename": "main.go", "status": "added", "patch": "@@ -0,0 +1,2 @@\n+line1\n+line3"}},
+		})
+	})
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "blamewarrior").Return("test-token", nil)
+
+	blame := github.NewGithubBlame(ts)
+
+	ctx := github.Context{context.Background(), baseURL}
+
+	authorship, err := blame.Authorship(ctx, "blamewarrior/hooks", 1347)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int{"alice": 1, "bob": 1}, authorship)
+}
+
+func TestGithubBlame_Authorship_MaxRequests(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/pulls/1347", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{"number": 1347, "base": map[string]string{"sha": "base"}})
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/pulls/1347/files", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, []map[string]string{
+			{"filename": "main.go", "status": "modified", "patch": "@@ -1,4 +1,3 @@\n line1\n-line2\n-line3\n+line23\n line4"},
+			{"filename": "hooks.go", "status": "modified", "patch": "@@ -1,2 +1,2 @@\n-line1\n+line1.1\n line2"},
+		})
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/commits", func(w http.ResponseWriter, r *http.Request) {
+		// file with most touched lines is blamed first
+		require.Equal(t, "main.go", r.URL.Query().Get("path"))
+
+		writeJSON(t, w, []map[string]interface{}{
+			{"sha": "c2", "author": map[string]string{"login": "bob"}, "parents": []map[string]string{{"sha": "c1"}}},
+			{"sha": "c1", "author": map[string]string{"login": "alice"}},
+		})
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/commits/c2", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{
+			"sha":   "c2",
+			"files": []map[string]string{{"filename": "main.go", "status": "modified", "patch": "@@ -1,2 +1,3 @@\n line1\n+line2\n line3"}},
+		})
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/commits/c1", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("requests limit exceeded")
+	})
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "blamewarrior").Return("test-token", nil)
+
+	blame := github.NewGithubBlame(ts)
+	blame.MaxRequests = 2
+
+	ctx := github.Context{context.Background(), baseURL}
+
+	authorship, err := blame.Authorship(ctx, "blamewarrior/hooks", 1347)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int{"bob": 1}, authorship)
+}
+
+func TestGithubBlame_Authorship_MaxFiles(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/pulls/1347", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{"number": 1347, "base": map[string]string{"sha": "base"}})
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/pulls/1347/files", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, []map[string]string{
+			{"filename": "hooks.go", "status": "modified", "patch": "@@ -1,2 +1,2 @@\n-line1\n+line1.1\n line2"},
+			{"filename": "main.go", "status": "modified", "patch": "@@ -1,4 +1,3 @@\n line1\n-line2\n-line3\n+line23\n line4"},
+		})
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/commits", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "main.go", r.URL.Query().Get("path"))
+
+		writeJSON(t, w, []map[string]interface{}{
+			{"sha": "c1", "author": map[string]string{"login": "alice"}},
+		})
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/commits/c1", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{
+			"sha":   "c1",
+			"files": []map[string]string{{"filename": "main.go", "status": "added", "patch": "@@ -0,0 +1,4 @@\n+line1\n+line2\n+line3\n+line4"}},
+		})
+	})
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "blamewarrior").Return("test-token", nil)
+
+	blame := github.NewGithubBlame(ts)
+	blame.MaxFiles = 1
+
+	ctx := github.Context{context.Background(), baseURL}
+
+	authorship, err := blame.Authorship(ctx, "blamewarrior/hooks", 1347)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int{"alice": 2}, authorship)
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	require.NoError(t, json.NewEncoder(w).Encode(v))
+}
+
+const testPatch = `@@ -1,4 +1,3 @@
+ line1
+-line2
+-line3
++line23
+ line4
+@@ -10,2 +9,4 @@ func main() {
+ line10
++line10.1
++line10.2
+ line11
+\ No newline at end of file`
@@ -0,0 +1,313 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/blamewarrior/hooks/blamewarrior/tokens"
+	gh "github.com/google/go-github/github"
+)
+
+// CodeOwnersLocations lists the paths CODEOWNERS file is looked up at, in order of precedence.
+var CodeOwnersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type CodeOwners interface {
+	Owners(ctx Context, repoFullName string, pullNumber int) ([]string, error)
+}
+
+type GithubCodeOwners struct {
+	APIConfig
+
+	tokenClient tokens.Client
+}
+
+func NewGithubCodeOwners(tokenClient tokens.Client) *GithubCodeOwners {
+	return &GithubCodeOwners{APIConfig: DefaultAPIConfig, tokenClient: tokenClient}
+}
+
+// Owners returns logins of code owners of files changed by pull request. Team
+// owners are expanded into their members, email owners are skipped.
+func (service *GithubCodeOwners) Owners(ctx Context, repoFullName string, pullNumber int) ([]string, error) {
+	owner, repo := SplitRepositoryName(repoFullName)
+
+	api, err := initAPIClient(ctx, service.tokenClient, repoFullName, service.APIConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := fetchCodeOwners(ctx, api, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	files, err := listPullRequestFiles(ctx, api, owner, repo, pullNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	logins := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, file := range files {
+		for _, fileOwner := range rules.Owners(file.GetFilename()) {
+			if seen[fileOwner] {
+				continue
+			}
+			seen[fileOwner] = true
+
+			members, err := resolveOwner(ctx, api, fileOwner)
+			if err != nil {
+				return nil, err
+			}
+
+			logins = append(logins, members...)
+		}
+	}
+
+	return logins, nil
+}
+
+func fetchCodeOwners(ctx Context, api *gh.Client, owner, repo string) (CodeOwnersRules, error) {
+	for _, path := range CodeOwnersLocations {
+		file, _, _, err := api.Repositories.GetContents(ctx, owner, repo, path, nil)
+		if err != nil {
+			if apiErr, ok := err.(*gh.ErrorResponse); ok && apiErr.Response.StatusCode == http.StatusNotFound {
+				continue
+			}
+
+			return nil, apiError(err)
+		}
+
+		if file == nil {
+			continue
+		}
+
+		content, err := file.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode %s: %s", path, err)
+		}
+
+		return ParseCodeOwners(content), nil
+	}
+
+	return nil, nil
+}
+
+func listPullRequestFiles(ctx Context, api *gh.Client, owner, repo string, pullNumber int) ([]*gh.CommitFile, error) {
+	files := make([]*gh.CommitFile, 0)
+
+	opt := &gh.ListOptions{PerPage: 100}
+	for {
+		pageFiles, resp, err := api.PullRequests.ListFiles(ctx, owner, repo, pullNumber, opt)
+		if err != nil {
+			return nil, apiError(err)
+		}
+
+		files = append(files, pageFiles...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return files, nil
+}
+
+// resolveOwner turns CODEOWNERS owner into logins, "@org/team" owners are resolved
+// into team members.
+func resolveOwner(ctx Context, api *gh.Client, owner string) ([]string, error) {
+	if !strings.HasPrefix(owner, "@") {
+		return nil, nil
+	}
+
+	org, slug := SplitRepositoryName(owner[1:])
+	if org == "" {
+		return []string{owner[1:]}, nil
+	}
+
+	team, err := findTeam(ctx, api, org, slug)
+	if err != nil || team == nil {
+		return nil, err
+	}
+
+	logins := make([]string, 0)
+
+	opt := &gh.OrganizationListTeamMembersOptions{ListOptions: gh.ListOptions{PerPage: 100}}
+	for {
+		members, resp, err := api.Organizations.ListTeamMembers(ctx, team.GetID(), opt)
+		if err != nil {
+			return nil, apiError(err)
+		}
+
+		for _, member := range members {
+			logins = append(logins, member.GetLogin())
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return logins, nil
+}
+
+func findTeam(ctx Context, api *gh.Client, org, slug string) (*gh.Team, error) {
+	opt := &gh.ListOptions{PerPage: 100}
+	for {
+		teams, resp, err := api.Organizations.ListTeams(ctx, org, opt)
+		if err != nil {
+			return nil, apiError(err)
+		}
+
+		for _, team := range teams {
+			if strings.EqualFold(team.GetSlug(), slug) {
+				return team, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+func apiError(err error) error {
+	switch err := err.(type) {
+	case *gh.RateLimitError:
+		return ErrRateLimitReached
+	case *gh.ErrorResponse:
+		if err.Response.StatusCode == http.StatusNotFound {
+			return ErrNoSuchRepository
+		}
+	}
+
+	return fmt.Errorf("request failed: %s", err)
+}
+
+// CodeOwnersRule assigns owners to files matching the pattern.
+type CodeOwnersRule struct {
+	Pattern string
+	Owners  []string
+
+	re *regexp.Regexp
+}
+
+type CodeOwnersRules []CodeOwnersRule
+
+// ParseCodeOwners parses CODEOWNERS file content, malformed patterns are skipped.
+func ParseCodeOwners(content string) CodeOwnersRules {
+	rules := make(CodeOwnersRules, 0)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if i := strings.Index(line, " #"); i != -1 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+
+		re, err := compileCodeOwnersPattern(fields[0])
+		if err != nil {
+			continue
+		}
+
+		rules = append(rules, CodeOwnersRule{Pattern: fields[0], Owners: fields[1:], re: re})
+	}
+
+	return rules
+}
+
+// Owners returns owners of the file at path. As in GitHub, the last matching rule wins.
+func (rules CodeOwnersRules) Owners(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].re.MatchString(path) {
+			return rules[i].Owners
+		}
+	}
+
+	return nil
+}
+
+// compileCodeOwnersPattern converts gitignore-style pattern into regular expression
+func compileCodeOwnersPattern(pattern string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	var expr bytes.Buffer
+
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	switch {
+	case dirOnly:
+		expr.WriteString("/.*$")
+	case strings.HasSuffix(pattern, "/*"):
+		// "docs/*" matches files in docs directory but not in its subdirectories
+		expr.WriteString("$")
+	default:
+		expr.WriteString("(/.*)?$")
+	}
+
+	return regexp.Compile(expr.String())
+}
@@ -0,0 +1,138 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package github_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/blamewarrior/hooks/github"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeOwnersRules_Owners(t *testing.T) {
+	rules := github.ParseCodeOwners(codeOwnersFile)
+
+	results := []struct {
+		Path   string
+		Owners []string
+	}{
+		{"README.md", []string{"@blamewarrior/core"}},
+		{"main.go", []string{"@golang-owner"}},
+		{"github/github.go", []string{"@golang-owner"}},
+		{"cmd/api/main.go", []string{"@api-owner", "api@blamewarrior.com"}},
+		{"build/logs/today.log", []string{"@logs-owner"}},
+		{"src/build/logs/today.log", []string{"@blamewarrior/core"}},
+		{"docs/index.md", []string{"@docs-owner"}},
+		{"docs/api/index.md", []string{"@blamewarrior/core"}},
+		{"vendor/apps/app.txt", []string{"@apps-owner"}},
+	}
+
+	for _, result := range results {
+		assert.Equal(t, result.Owners, rules.Owners(result.Path), result.Path)
+	}
+}
+
+func TestGithubCodeOwners_Owners(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/contents/.github/CODEOWNERS", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Not Found"}`)
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/contents/CODEOWNERS", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+
+		fmt.Fprintf(w, `{"type":"file","encoding":"base64","path":"CODEOWNERS","content":%q}`,
+			base64.StdEncoding.EncodeToString([]byte(codeOwnersFile)))
+	})
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/pulls/1347/files", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+
+		fmt.Fprint(w, `[{"filename":"README.md"},{"filename":"cmd/api/main.go"},{"filename":"cmd/api/config.go"}]`)
+	})
+
+	mux.HandleFunc("/orgs/blamewarrior/teams", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+
+		fmt.Fprint(w, `[{"id":1,"slug":"ops"},{"id":7,"slug":"core"}]`)
+	})
+
+	mux.HandleFunc("/teams/7/members", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+
+		fmt.Fprint(w, `[{"login":"alice"},{"login":"bob"}]`)
+	})
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "blamewarrior").Return("test-token", nil)
+
+	codeOwners := github.NewGithubCodeOwners(ts)
+
+	ctx := github.Context{context.Background(), baseURL}
+
+	owners, err := codeOwners.Owners(ctx, "blamewarrior/hooks", 1347)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"alice", "bob", "api-owner"}, owners)
+}
+
+func TestGithubCodeOwners_Owners_NoCodeOwners(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	for _, path := range github.CodeOwnersLocations {
+		mux.HandleFunc("/repos/blamewarrior/hooks/contents/"+path, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+		})
+	}
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "blamewarrior").Return("test-token", nil)
+
+	codeOwners := github.NewGithubCodeOwners(ts)
+
+	ctx := github.Context{context.Background(), baseURL}
+
+	owners, err := codeOwners.Owners(ctx, "blamewarrior/hooks", 1347)
+	require.NoError(t, err)
+
+	assert.Empty(t, owners)
+}
+
+const codeOwnersFile = `# Default owners
+*       @blamewarrior/core
+
+*.go    @golang-owner
+
+/cmd/api/ @api-owner api@blamewarrior.com # inline comment
+/build/logs/ @logs-owner
+docs/*  @docs-owner
+apps/   @apps-owner
+`
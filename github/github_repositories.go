@@ -18,6 +18,7 @@ package github
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/blamewarrior/hooks/blamewarrior/tokens"
@@ -30,25 +31,31 @@ var (
 )
 
 type Repositories interface {
-	Track(ctx Context, repoFullName, callbackURL string) error
+	Track(ctx Context, repoFullName, callbackURL, secret string) error
 	Untrack(ctx Context, repoFullName, callbackURL string) error
+	Ping(ctx Context, repoFullName, callbackURL string) error
 }
 
 type GithubRepositories struct {
+	APIConfig
+
 	tokenClient tokens.Client
 }
 
 // NewClient returns a new copy of github repositories service that uses given http.Client
 // to make GitHub API requests.
 func NewGithubRepositories(tokenClient tokens.Client) *GithubRepositories {
-	return &GithubRepositories{tokenClient}
+	return &GithubRepositories{APIConfig: DefaultAPIConfig, tokenClient: tokenClient}
 }
 
-// Tracks pull requests sets up "pull_request" event to be sent to callback
-func (service *GithubRepositories) Track(ctx Context, repoFullName, callbackURL string) (err error) {
+// Tracks pull requests sets up "pull_request", "pull_request_review", "pull_request_review_comment",
+// "issue_comment" and "member" events to be sent to callback,
+// deliveries are signed with given secret. Hook that already exists for the callback
+// is updated to use the secret.
+func (service *GithubRepositories) Track(ctx Context, repoFullName, callbackURL, secret string) (err error) {
 	owner, name := SplitRepositoryName(repoFullName)
 
-	api, err := initAPIClient(ctx, service.tokenClient, owner)
+	api, err := initAPIClient(ctx, service.tokenClient, repoFullName, service.APIConfig)
 	if err != nil {
 		return err
 	}
@@ -56,10 +63,11 @@ func (service *GithubRepositories) Track(ctx Context, repoFullName, callbackURL
 	hook := &gh.Hook{
 		Name:   new(string),
 		Active: new(bool),
-		Events: []string{"pull_request", "member"},
+		Events: []string{"pull_request", "pull_request_review", "pull_request_review_comment", "issue_comment", "member"},
 		Config: map[string]interface{}{
 			"url":          callbackURL,
 			"content_type": "json",
+			"secret":       secret,
 		},
 	}
 
@@ -67,33 +75,74 @@ func (service *GithubRepositories) Track(ctx Context, repoFullName, callbackURL
 	*hook.Active = true
 
 	_, _, err = api.Repositories.CreateHook(ctx, owner, name, hook)
+	if apiErr, ok := err.(*gh.ErrorResponse); !ok || apiErr.Response.StatusCode != http.StatusUnprocessableEntity {
+		return err
+	}
+
+	// GitHub refuses to create another hook for the same URL
+	existing, err := findHook(ctx, api, repoFullName, callbackURL)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = api.Repositories.EditHook(ctx, owner, name, *existing.ID, hook)
 	return err
 }
 
 func (service *GithubRepositories) Untrack(ctx Context, repoFullName, callbackURL string) (err error) {
 	owner, name := SplitRepositoryName(repoFullName)
 
-	api, err := initAPIClient(ctx, service.tokenClient, owner)
+	api, err := initAPIClient(ctx, service.tokenClient, repoFullName, service.APIConfig)
 	if err != nil {
 		return err
 	}
 
-	hooks, _, err := api.Repositories.ListHooks(ctx, owner, name, nil)
+	hook, err := findHook(ctx, api, repoFullName, callbackURL)
+	if err != nil {
+		return err
+	}
 
+	_, err = api.Repositories.DeleteHook(ctx, owner, name, *hook.ID)
+	return err
+}
+
+// Ping asks GitHub to send ping event to the hook delivering to callback.
+func (service *GithubRepositories) Ping(ctx Context, repoFullName, callbackURL string) (err error) {
+	owner, name := SplitRepositoryName(repoFullName)
+
+	api, err := initAPIClient(ctx, service.tokenClient, repoFullName, service.APIConfig)
+	if err != nil {
+		return err
+	}
+
+	hook, err := findHook(ctx, api, repoFullName, callbackURL)
 	if err != nil {
 		return err
 	}
 
+	_, err = api.Repositories.PingHook(ctx, owner, name, *hook.ID)
+	return err
+}
+
+// findHook returns repository hook delivering to callbackURL
+func findHook(ctx Context, api *gh.Client, repoFullName, callbackURL string) (*gh.Hook, error) {
+	owner, name := SplitRepositoryName(repoFullName)
+
+	hooks, _, err := api.Repositories.ListHooks(ctx, owner, name, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
 	for _, hook := range hooks {
 
 		configURL := hook.Config["url"]
 
-		if strings.Index(*hook.URL, repoFullName) != -1 &&
+		if strings.Index(*hook.URL, owner+"/"+name) != -1 &&
 			configURL == callbackURL {
-			_, err = api.Repositories.DeleteHook(ctx, owner, name, *hook.ID)
-			return err
+			return hook, nil
 		}
 	}
 
-	return fmt.Errorf("Hook not found")
+	return nil, fmt.Errorf("Hook not found")
 }
@@ -48,7 +48,11 @@ func TestTrackRepositoryPullRequests(t *testing.T) {
 
 		assert.Equal(t, *hook.Name, "web")
 		assert.Contains(t, hook.Events, "pull_request")
+		assert.Contains(t, hook.Events, "pull_request_review")
+		assert.Contains(t, hook.Events, "pull_request_review_comment")
+		assert.Contains(t, hook.Events, "issue_comment")
 		assert.Equal(t, hook.Config["url"], "https://example.com/blamewarrior/hooks/webhook")
+		assert.Equal(t, hook.Config["secret"], "s3cr3t")
 		assert.True(t, *hook.Active)
 
 		fmt.Fprint(w, `{"id":1}`)
@@ -64,11 +68,87 @@ func TestTrackRepositoryPullRequests(t *testing.T) {
 
 	ctx := github.Context{context.Background(), baseURL}
 
-	err := githubRepos.Track(ctx, "blamewarrior/hooks", callbackURL)
+	err := githubRepos.Track(ctx, "blamewarrior/hooks", callbackURL, "s3cr3t")
 	require.NoError(t, err)
 
 }
 
+func TestTrackRepositoryPullRequests_HookExists(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/hooks", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"message":"Validation Failed","errors":[{"resource":"Hook","code":"custom","message":"Hook already exists on this repository"}]}`)
+		case "GET":
+			fmt.Fprint(w, hooksResponse)
+		default:
+			t.Errorf("unexpected %s request", r.Method)
+		}
+	})
+
+	var edited bool
+	mux.HandleFunc("/repos/blamewarrior/hooks/hooks/1", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PATCH", r.Method)
+
+		var hook api.Hook
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&hook))
+
+		assert.Equal(t, "https://example.com/blamewarrior/hooks/webhook", hook.Config["url"])
+		assert.Equal(t, "n3w-s3cr3t", hook.Config["secret"])
+
+		edited = true
+		fmt.Fprint(w, `{"id":1}`)
+	})
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "blamewarrior").Return("test-token", nil)
+
+	githubRepos := github.NewGithubRepositories(ts)
+
+	ctx := github.Context{context.Background(), baseURL}
+
+	err := githubRepos.Track(ctx, "blamewarrior/hooks", "https://example.com/blamewarrior/hooks/webhook", "n3w-s3cr3t")
+	require.NoError(t, err)
+
+	assert.True(t, edited)
+}
+
+func TestPingRepositoryHook(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/blamewarrior/hooks/hooks", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, r.Method, "GET")
+
+		fmt.Fprint(w, hooksResponse)
+	})
+
+	var pinged bool
+	mux.HandleFunc("/repos/blamewarrior/hooks/hooks/1/pings", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+
+		pinged = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "blamewarrior").Return("test-token", nil)
+
+	githubRepos := github.NewGithubRepositories(ts)
+
+	ctx := github.Context{context.Background(), baseURL}
+
+	err := githubRepos.Ping(ctx, "blamewarrior/hooks", "https://example.com/blamewarrior/hooks/webhook")
+	require.NoError(t, err)
+
+	assert.True(t, pinged)
+}
+
 func TestUntrackRepositoryPullRequests(t *testing.T) {
 	mux, baseURL, teardown := setup()
 	defer teardown()
@@ -98,6 +178,39 @@ func TestUntrackRepositoryPullRequests(t *testing.T) {
 
 }
 
+func TestTrackRepositoryPullRequests_Enterprise(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/v3/repos/blamewarrior/hooks/hooks", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, r.Method, "POST")
+
+		var hook api.Hook
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&hook))
+
+		assert.Equal(t, hook.Config["url"], "https://hooks.example.com/blamewarrior/hooks/webhook")
+
+		fmt.Fprint(w, `{"id":1}`)
+	})
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "blamewarrior").Return("test-token", nil)
+
+	config, err := github.NewAPIConfig(baseURL.String(), "")
+	require.NoError(t, err)
+
+	githubRepos := github.NewGithubRepositories(ts)
+	githubRepos.APIConfig = config
+
+	callbackURL := "https://hooks.example.com/blamewarrior/hooks/webhook"
+
+	err = githubRepos.Track(github.Context{Context: context.Background()}, "github.example.com/blamewarrior/hooks", callbackURL, "s3cr3t")
+	require.NoError(t, err)
+
+	ts.AssertExpectations(t)
+}
+
 func setup() (mux *http.ServeMux, baseURL *url.URL, teardownFn func()) {
 	mux = http.NewServeMux()
 	server := httptest.NewServer(mux)
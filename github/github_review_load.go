@@ -0,0 +1,63 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package github
+
+import (
+	"fmt"
+
+	"github.com/blamewarrior/hooks/blamewarrior/tokens"
+	gh "github.com/google/go-github/github"
+)
+
+// ReviewLoad reports the number of open pull requests reviewers are requested to review.
+type ReviewLoad interface {
+	PendingReviews(ctx Context, repoFullName string, logins []string) (map[string]int, error)
+}
+
+type GithubReviewLoad struct {
+	APIConfig
+
+	tokenClient tokens.Client
+}
+
+func NewGithubReviewLoad(tokenClient tokens.Client) *GithubReviewLoad {
+	return &GithubReviewLoad{APIConfig: DefaultAPIConfig, tokenClient: tokenClient}
+}
+
+// PendingReviews returns the number of open pull requests each of logins is requested
+// to review. Pull requests are searched with the token used for repository, so only
+// those visible to it are counted.
+func (service *GithubReviewLoad) PendingReviews(ctx Context, repoFullName string, logins []string) (map[string]int, error) {
+	api, err := initAPIClient(ctx, service.tokenClient, repoFullName, service.APIConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	load := make(map[string]int, len(logins))
+
+	for _, login := range logins {
+		query := fmt.Sprintf("is:pr is:open review-requested:%s", login)
+
+		result, _, err := api.Search.Issues(ctx, query, &gh.SearchOptions{ListOptions: gh.ListOptions{PerPage: 1}})
+		if err != nil {
+			return nil, apiError(err)
+		}
+
+		load[login] = result.GetTotal()
+	}
+
+	return load, nil
+}
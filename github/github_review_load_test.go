@@ -0,0 +1,78 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package github_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/blamewarrior/hooks/github"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGithubReviewLoad_PendingReviews(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	totals := map[string]int{"alice": 3, "bob": 0}
+
+	mux.HandleFunc("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		var login string
+		_, err := fmt.Sscanf(r.URL.Query().Get("q"), "is:pr is:open review-requested:%s", &login)
+		require.NoError(t, err)
+
+		fmt.Fprintf(w, `{"total_count":%d,"incomplete_results":false,"items":[]}`, totals[login])
+	})
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "blamewarrior").Return("test-token", nil)
+
+	load := github.NewGithubReviewLoad(ts)
+
+	ctx := github.Context{context.Background(), baseURL}
+
+	result, err := load.PendingReviews(ctx, "blamewarrior/hooks", []string{"alice", "bob"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int{"alice": 3, "bob": 0}, result)
+}
+
+func TestGithubReviewLoad_PendingReviews_RateLimit(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"API rate limit exceeded"}`)
+	})
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "blamewarrior").Return("test-token", nil)
+
+	load := github.NewGithubReviewLoad(ts)
+
+	ctx := github.Context{context.Background(), baseURL}
+
+	_, err := load.PendingReviews(ctx, "blamewarrior/hooks", []string{"alice"})
+	assert.Equal(t, github.ErrRateLimitReached, err)
+}
@@ -25,15 +25,19 @@ import (
 
 type Reviewers interface {
 	RequestReviewers(ctx Context, repoFullName string, pullNumber int, reviewers []Collaborator) (err error)
+	RequestTeamReviewers(ctx Context, repoFullName string, pullNumber int, teamSlugs []string) (err error)
+	RemoveTeamReviewers(ctx Context, repoFullName string, pullNumber int, teamSlugs []string) (err error)
 	ReviewComments(ctx Context, repoFullName string, pullNumber int) ([]ReviewComment, error)
 }
 
 type GithubReviewers struct {
+	APIConfig
+
 	tokenClient tokens.Client
 }
 
 func NewGithubReviewers(tokenClient tokens.Client) *GithubReviewers {
-	return &GithubReviewers{tokenClient}
+	return &GithubReviewers{APIConfig: DefaultAPIConfig, tokenClient: tokenClient}
 }
 
 func (service *GithubReviewers) RequestReviewers(ctx Context, repoFullName string, pullNumber int, reviewers []Collaborator) (err error) {
@@ -46,7 +50,7 @@ func (service *GithubReviewers) RequestReviewers(ctx Context, repoFullName strin
 
 	owner, repo := SplitRepositoryName(repoFullName)
 
-	api, err := initAPIClient(ctx, service.tokenClient, owner)
+	api, err := initAPIClient(ctx, service.tokenClient, repoFullName, service.APIConfig)
 	if err != nil {
 		return err
 	}
@@ -60,10 +64,42 @@ func (service *GithubReviewers) RequestReviewers(ctx Context, repoFullName strin
 	return nil
 }
 
+func (service *GithubReviewers) RequestTeamReviewers(ctx Context, repoFullName string, pullNumber int, teamSlugs []string) (err error) {
+	owner, repo := SplitRepositoryName(repoFullName)
+
+	api, err := initAPIClient(ctx, service.tokenClient, repoFullName, service.APIConfig)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = api.PullRequests.RequestReviewers(ctx, owner, repo, pullNumber, gh.ReviewersRequest{TeamReviewers: teamSlugs})
+	if err != nil {
+		return apiError(err)
+	}
+
+	return nil
+}
+
+func (service *GithubReviewers) RemoveTeamReviewers(ctx Context, repoFullName string, pullNumber int, teamSlugs []string) (err error) {
+	owner, repo := SplitRepositoryName(repoFullName)
+
+	api, err := initAPIClient(ctx, service.tokenClient, repoFullName, service.APIConfig)
+	if err != nil {
+		return err
+	}
+
+	_, err = api.PullRequests.RemoveReviewers(ctx, owner, repo, pullNumber, gh.ReviewersRequest{TeamReviewers: teamSlugs})
+	if err != nil {
+		return apiError(err)
+	}
+
+	return nil
+}
+
 func (service *GithubReviewers) ReviewComments(ctx Context, repoFullName string, pullNumber int) ([]ReviewComment, error) {
 	owner, repo := SplitRepositoryName(repoFullName)
 
-	api, err := initAPIClient(ctx, service.tokenClient, owner)
+	api, err := initAPIClient(ctx, service.tokenClient, repoFullName, service.APIConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -18,6 +18,7 @@ package github_test
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"testing"
 
@@ -57,6 +58,61 @@ func TestGithubReviewers_RequestReviewers(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestGithubReviewers_RequestTeamReviewers(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/octocat/Hello-World/pulls/1347/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"team_reviewers":["justice-league"]}`, string(body))
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, requestedReviewersResponse)
+	})
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "octocat").Return("test-token", nil)
+
+	githubReviewers := github.NewGithubReviewers(ts)
+
+	ctx := github.Context{context.Background(), baseURL}
+
+	err := githubReviewers.RequestTeamReviewers(ctx, "octocat/Hello-World", 1347, []string{"justice-league"})
+	require.NoError(t, err)
+}
+
+func TestGithubReviewers_RemoveTeamReviewers(t *testing.T) {
+	mux, baseURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/octocat/Hello-World/pulls/1347/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"team_reviewers":["justice-league"]}`, string(body))
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ts := new(tokenServiceMock)
+
+	ts.On("GetToken", "octocat").Return("test-token", nil)
+
+	githubReviewers := github.NewGithubReviewers(ts)
+
+	ctx := github.Context{context.Background(), baseURL}
+
+	err := githubReviewers.RemoveTeamReviewers(ctx, "octocat/Hello-World", 1347, []string{"justice-league"})
+	require.NoError(t, err)
+}
+
 func TestGithubReviewers_ReviewComments(t *testing.T) {
 	mux, baseURL, teardown := setup()
 	defer teardown()
@@ -0,0 +1,68 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strings"
+)
+
+var (
+	ErrMissingSignature = errors.New("missing payload signature")
+	ErrInvalidSignature = errors.New("invalid payload signature")
+)
+
+// ValidateSignature checks the payload against X-Hub-Signature-256 and X-Hub-Signature
+// header values. The SHA-256 signature takes precedence when both are present.
+func ValidateSignature(payload []byte, secret, signature256, signature string) error {
+	if secret == "" {
+		return ErrInvalidSignature
+	}
+
+	switch {
+	case signature256 != "":
+		return checkSignature(payload, secret, signature256, "sha256=", sha256.New)
+	case signature != "":
+		return checkSignature(payload, secret, signature, "sha1=", sha1.New)
+	default:
+		return ErrMissingSignature
+	}
+}
+
+func checkSignature(payload []byte, secret, signature, prefix string, hashFn func() hash.Hash) error {
+	if !strings.HasPrefix(signature, prefix) {
+		return ErrInvalidSignature
+	}
+
+	expectedMAC, err := hex.DecodeString(signature[len(prefix):])
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(hashFn, []byte(secret))
+	mac.Write(payload)
+
+	if !hmac.Equal(mac.Sum(nil), expectedMAC) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package github_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/blamewarrior/hooks/github"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSignature(t *testing.T) {
+	payload := []byte(`{"zen":"Keep it logically awesome."}`)
+
+	results := []struct {
+		Secret       string
+		Signature256 string
+		Signature    string
+		Err          error
+	}{
+		{
+			Secret:       "s3cr3t",
+			Signature256: "sha256=" + signPayload256("s3cr3t", payload),
+			Err:          nil,
+		},
+		{
+			Secret:    "s3cr3t",
+			Signature: "sha1=" + signPayload1("s3cr3t", payload),
+			Err:       nil,
+		},
+		{
+			Secret:       "s3cr3t",
+			Signature256: "sha256=" + signPayload256("other", payload),
+			Signature:    "sha1=" + signPayload1("s3cr3t", payload),
+			Err:          github.ErrInvalidSignature,
+		},
+		{
+			Secret:    "s3cr3t",
+			Signature: "sha256=" + signPayload256("s3cr3t", payload),
+			Err:       github.ErrInvalidSignature,
+		},
+		{
+			Secret:    "s3cr3t",
+			Signature: "sha1=not-a-hex",
+			Err:       github.ErrInvalidSignature,
+		},
+		{
+			Secret: "s3cr3t",
+			Err:    github.ErrMissingSignature,
+		},
+		{
+			Secret:       "",
+			Signature256: "sha256=" + signPayload256("", payload),
+			Err:          github.ErrInvalidSignature,
+		},
+	}
+
+	for _, result := range results {
+		err := github.ValidateSignature(payload, result.Secret, result.Signature256, result.Signature)
+		assert.Equal(t, result.Err, err)
+	}
+}
+
+func signPayload256(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func signPayload1(secret string, payload []byte) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
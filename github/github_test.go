@@ -1,14 +1,70 @@
 package github_test
 
 import (
+	"context"
+	"testing"
+
+	"github.com/blamewarrior/hooks/github"
+
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+func TestSplitRepositoryName(t *testing.T) {
+	examples := map[string][2]string{
+		"blamewarrior/hooks":                                 {"blamewarrior", "hooks"},
+		"github.example.com/blamewarrior/hooks":              {"blamewarrior", "hooks"},
+		"github.example.com:8443/blamewarrior/hooks":         {"blamewarrior", "hooks"},
+		"https://github.example.com/blamewarrior/hooks":      {"blamewarrior", "hooks"},
+		"https://github.example.com/blamewarrior/hooks.git/": {"blamewarrior", "hooks"},
+		"blamewarrior":             {"", ""},
+		"blamewarrior/":            {"", ""},
+		"/hooks":                   {"", ""},
+		"blamewarrior/hooks/pulls": {"", ""},
+		"https://github.example.com/blamewarrior": {"", ""},
+	}
+
+	for fullName, expected := range examples {
+		owner, repo := github.SplitRepositoryName(fullName)
+		assert.Equal(t, expected, [2]string{owner, repo}, fullName)
+	}
+}
+
+func TestNewAPIConfig(t *testing.T) {
+	examples := []struct {
+		BaseURL, UploadURL         string
+		ExpectedBase, ExpectedUpld string
+	}{
+		{"https://api.github.com", "", "https://api.github.com/", "https://uploads.github.com/"},
+		{"https://github.example.com", "", "https://github.example.com/api/v3/", "https://github.example.com/api/uploads/"},
+		{"https://github.example.com/api/v3", "", "https://github.example.com/api/v3/", "https://github.example.com/api/uploads/"},
+		{"https://github.example.com/api/v3/", "https://uploads.example.com", "https://github.example.com/api/v3/", "https://uploads.example.com/"},
+	}
+
+	for _, example := range examples {
+		config, err := github.NewAPIConfig(example.BaseURL, example.UploadURL)
+		require.NoError(t, err)
+
+		assert.Equal(t, example.ExpectedBase, config.BaseURL.String())
+		assert.Equal(t, example.ExpectedUpld, config.UploadURL.String())
+		assert.Equal(t, github.DefaultTimeout, config.Timeout)
+	}
+
+	for _, baseURL := range []string{"", "github.example.com", "ftp://github.example.com/", "%"} {
+		_, err := github.NewAPIConfig(baseURL, "")
+		assert.Error(t, err, baseURL)
+	}
+
+	_, err := github.NewAPIConfig("https://github.example.com", "/uploads")
+	assert.Error(t, err)
+}
+
 type tokenServiceMock struct {
 	mock.Mock
 }
 
-func (tsMock *tokenServiceMock) GetToken(nickname string) (string, error) {
+func (tsMock *tokenServiceMock) GetToken(ctx context.Context, nickname string) (string, error) {
 	args := tsMock.Called(nickname)
 	return args.String(0), args.Error(1)
 
@@ -0,0 +1,62 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+import (
+	"context"
+	"sort"
+
+	bw "github.com/blamewarrior/hooks/blamewarrior"
+	gh "github.com/blamewarrior/hooks/github"
+	"github.com/blamewarrior/hooks/logging"
+)
+
+// LeastLoadedPicker picks the candidates with the fewest open pull requests they are
+// requested to review, ties are broken by login. All candidates are picked by fallback
+// picker if their load cannot be found.
+type LeastLoadedPicker struct {
+	load     gh.ReviewLoad
+	fallback ReviewerPicker
+}
+
+func NewLeastLoadedPicker(load gh.ReviewLoad, fallback ReviewerPicker) *LeastLoadedPicker {
+	return &LeastLoadedPicker{load, fallback}
+}
+
+func (picker *LeastLoadedPicker) Pick(ctx context.Context, pullRequest *bw.PullRequest, candidates []gh.Collaborator, n int) ([]gh.Collaborator, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNoEligibleReviewer
+	}
+
+	sorted := sortedByLogin(candidates)
+
+	logins := make([]string, 0, len(sorted))
+	for _, candidate := range sorted {
+		logins = append(logins, candidate.Login)
+	}
+
+	load, err := picker.load.PendingReviews(gh.Context{Context: ctx}, pullRequest.RepositoryName, logins)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Warnf("failed to find review load, picking reviewers without it")
+		return picker.fallback.Pick(ctx, pullRequest, candidates, n)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return load[sorted[i].Login] < load[sorted[j].Login]
+	})
+
+	return sorted[:minInt(n, len(sorted))], nil
+}
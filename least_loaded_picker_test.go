@@ -0,0 +1,87 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blamewarrior/hooks"
+	bw "github.com/blamewarrior/hooks/blamewarrior"
+	gh "github.com/blamewarrior/hooks/github"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeastLoadedPicker_Pick(t *testing.T) {
+	pullRequest := &bw.PullRequest{RepositoryName: "blamewarrior/hooks", Number: 1347}
+
+	results := []struct {
+		Load   map[string]int
+		N      int
+		Logins []string
+	}{
+		{map[string]int{"alice": 3, "bob": 0, "carol": 1, "dave": 2}, 1, []string{"bob"}},
+		{map[string]int{"alice": 3, "bob": 0, "carol": 1, "dave": 2}, 2, []string{"bob", "carol"}},
+		{map[string]int{"alice": 1, "bob": 1, "carol": 1, "dave": 0}, 2, []string{"dave", "alice"}},
+		{map[string]int{}, 5, []string{"alice", "bob", "carol", "dave"}},
+	}
+
+	for _, result := range results {
+		load := new(ReviewLoadMock)
+		load.On("PendingReviews", mock.Anything, "blamewarrior/hooks", []string{"alice", "bob", "carol", "dave"}).Return(result.Load, nil)
+
+		picker := hooks.NewLeastLoadedPicker(load, hooks.NewRoundRobinPicker())
+
+		reviewers, err := picker.Pick(context.Background(), pullRequest, testCandidates, result.N)
+		require.NoError(t, err)
+
+		assert.Equal(t, result.Logins, logins(reviewers))
+	}
+
+	_, err := hooks.NewLeastLoadedPicker(new(ReviewLoadMock), hooks.NewRoundRobinPicker()).Pick(context.Background(), pullRequest, nil, 1)
+	assert.Equal(t, hooks.ErrNoEligibleReviewer, err)
+}
+
+func TestLeastLoadedPicker_Pick_Error(t *testing.T) {
+	pullRequest := &bw.PullRequest{RepositoryName: "blamewarrior/hooks", Number: 1347}
+
+	load := new(ReviewLoadMock)
+	load.On("PendingReviews", mock.Anything, "blamewarrior/hooks", mock.Anything).Return(nil, errors.New("rate limit"))
+
+	picker := hooks.NewLeastLoadedPicker(load, hooks.NewRoundRobinPicker())
+
+	// reviewers are still picked, just not by their load
+	reviewers, err := picker.Pick(context.Background(), pullRequest, testCandidates, 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"alice", "bob"}, logins(reviewers))
+}
+
+type ReviewLoadMock struct {
+	mock.Mock
+}
+
+func (m *ReviewLoadMock) PendingReviews(ctx gh.Context, repoFullName string, logins []string) (map[string]int, error) {
+	args := m.Called(ctx, repoFullName, logins)
+
+	load, _ := args.Get(0).(map[string]int)
+
+	return load, args.Error(1)
+}
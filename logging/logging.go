@@ -0,0 +1,205 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package logging implements leveled logger writing JSON lines. Loggers carry
+// fields added to every line they write and are passed around in context.Context,
+// so that lines written while handling a delivery can be correlated.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (level Level) String() string {
+	if level < DebugLevel || level > ErrorLevel {
+		return fmt.Sprintf("level(%d)", int(level))
+	}
+
+	return levelNames[level]
+}
+
+// ParseLevel parses level name, e.g. "info".
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+
+	return InfoLevel, fmt.Errorf("unknown log level %q", s)
+}
+
+// Fields are key-value pairs written along with the message.
+type Fields map[string]interface{}
+
+// Default is used when context carries no logger.
+var Default = New(os.Stderr, InfoLevel)
+
+type Logger struct {
+	level  Level
+	fields Fields
+
+	mu  *sync.Mutex
+	out io.Writer
+	now func() time.Time
+}
+
+// New returns logger writing lines of level and above to out.
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{
+		level:  level,
+		fields: Fields{},
+		mu:     new(sync.Mutex),
+		out:    out,
+		now:    time.Now,
+	}
+}
+
+// With returns logger that writes key along with its own fields.
+func (logger *Logger) With(key string, value interface{}) *Logger {
+	return logger.WithFields(Fields{key: value})
+}
+
+// WithFields returns logger that writes fields along with its own ones, fields
+// with the same keys are overridden.
+func (logger *Logger) WithFields(fields Fields) *Logger {
+	child := *logger
+	child.fields = make(Fields, len(logger.fields)+len(fields))
+
+	for k, v := range logger.fields {
+		child.fields[k] = v
+	}
+
+	for k, v := range fields {
+		child.fields[k] = v
+	}
+
+	return &child
+}
+
+// WithError returns logger that writes err in "error" field.
+func (logger *Logger) WithError(err error) *Logger {
+	if err == nil {
+		return logger
+	}
+
+	return logger.With("error", err.Error())
+}
+
+func (logger *Logger) Debugf(format string, args ...interface{}) {
+	logger.log(DebugLevel, format, args)
+}
+
+func (logger *Logger) Infof(format string, args ...interface{}) {
+	logger.log(InfoLevel, format, args)
+}
+
+func (logger *Logger) Warnf(format string, args ...interface{}) {
+	logger.log(WarnLevel, format, args)
+}
+
+func (logger *Logger) Errorf(format string, args ...interface{}) {
+	logger.log(ErrorLevel, format, args)
+}
+
+// Fatalf writes the message at error level and exits.
+func (logger *Logger) Fatalf(format string, args ...interface{}) {
+	logger.log(ErrorLevel, format, args)
+	os.Exit(1)
+}
+
+func (logger *Logger) log(level Level, format string, args []interface{}) {
+	if level < logger.level {
+		return
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(`{"time":`)
+	writeJSON(&buf, logger.now().UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSON(&buf, level.String())
+	buf.WriteString(`,"msg":`)
+	writeJSON(&buf, fmt.Sprintf(format, args...))
+
+	keys := make([]string, 0, len(logger.fields))
+	for k := range logger.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		buf.WriteByte(',')
+		writeJSON(&buf, k)
+		buf.WriteByte(':')
+		writeJSON(&buf, logger.fields[k])
+	}
+
+	buf.WriteString("}\n")
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	buf.WriteTo(logger.out)
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+
+	buf.Write(b)
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying logger.
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns logger carried by ctx or Default if there is none.
+func FromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return logger
+	}
+
+	return Default
+}
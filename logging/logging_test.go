@@ -0,0 +1,93 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blamewarrior/hooks/logging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := logging.New(&buf, logging.InfoLevel).
+		WithFields(logging.Fields{"delivery_id": "72d3162e", "event": "pull_request"}).
+		With("pull_request", 1)
+
+	logger.Debugf("skipped")
+	logger.WithError(errors.New("web service is down")).Errorf("failed to handle %s", "opened")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &line))
+
+	ts, err := time.Parse(time.RFC3339Nano, line["time"].(string))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), ts, time.Minute)
+
+	delete(line, "time")
+
+	assert.Equal(t, map[string]interface{}{
+		"level":        "error",
+		"msg":          "failed to handle opened",
+		"delivery_id":  "72d3162e",
+		"event":        "pull_request",
+		"pull_request": float64(1),
+		"error":        "web service is down",
+	}, line)
+}
+
+func TestLogger_WithFieldsDoesNotChangeParent(t *testing.T) {
+	var buf bytes.Buffer
+
+	parent := logging.New(&buf, logging.DebugLevel).With("event", "member")
+	parent.With("event", "ping").Infof("child")
+	parent.Infof("parent")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	assert.Contains(t, lines[0], `"event":"ping"`)
+	assert.Contains(t, lines[1], `"event":"member"`)
+}
+
+func TestContext(t *testing.T) {
+	assert.Equal(t, logging.Default, logging.FromContext(context.Background()))
+
+	logger := logging.New(new(bytes.Buffer), logging.InfoLevel)
+	assert.Equal(t, logger, logging.FromContext(logging.NewContext(context.Background(), logger)))
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := logging.ParseLevel("WARN")
+	require.NoError(t, err)
+	assert.Equal(t, logging.WarnLevel, level)
+
+	_, err = logging.ParseLevel("verbose")
+	assert.Error(t, err)
+}
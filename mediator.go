@@ -16,10 +16,12 @@
 package hooks
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math/rand"
 	"net/http"
+	"strings"
 	"time"
 
 	bw "github.com/blamewarrior/hooks/blamewarrior"
@@ -27,57 +29,267 @@ import (
 	"github.com/blamewarrior/hooks/blamewarrior/tokens"
 	"github.com/blamewarrior/hooks/blamewarrior/web"
 	gh "github.com/blamewarrior/hooks/github"
+	"github.com/blamewarrior/hooks/logging"
+	"github.com/blamewarrior/hooks/metrics"
 )
 
 var SendingError = fmt.Errorf("sending error")
 
+var ErrInvalidHook = errors.New("invalid hook configuration")
+
 type Mediator interface {
-	Mediate(event string, payload []byte) (err error)
+	Mediate(ctx context.Context, event, deliveryID string, payload []byte) (err error)
+	Supports(event, action string) bool
+}
+
+// SavedForRetryError is returned by Mediate when handling the delivery failed and
+// its payload has been saved to be retried later.
+type SavedForRetryError struct {
+	Err error
+}
+
+func (e *SavedForRetryError) Error() string {
+	return fmt.Sprintf("saved for retry: %s", e.Err)
 }
 
 type MediatorService struct {
 	ConsumerBaseURL string
 	c               *http.Client
 
-	payloads Payloads
+	// ReviewerAssignments counts reviewer assignments by outcome label if set
+	ReviewerAssignments *metrics.CounterVec
+
+	payloads  Payloads
+	trackings Trackings
 
 	webClient           web.Client
 	collaboratorsClient collaborators.Client
 	tokenClient         tokens.Client
 	reviewersClient     gh.Reviewers
+	reviewerPicker      ReviewerPicker
+	policies            *Policies
+
+	events     eventRegistry
+	middleware []Middleware
 }
 
 func NewMediatorService(
-	payloads Payloads, webClient web.Client,
-	collaboratorsClient collaborators.Client, reviewers gh.Reviewers) *MediatorService {
-	return &MediatorService{
+	payloads Payloads, deliveries Deliveries, trackings Trackings, webClient web.Client,
+	collaboratorsClient collaborators.Client, reviewers gh.Reviewers,
+	reviewerPicker ReviewerPicker, policies *Policies) *MediatorService {
+	service := &MediatorService{
 		payloads:            payloads,
+		trackings:           trackings,
 		webClient:           webClient,
 		collaboratorsClient: collaboratorsClient,
 		reviewersClient:     reviewers,
+		reviewerPicker:      reviewerPicker,
+		policies:            policies,
+		events:              make(eventRegistry),
 	}
+
+	service.Use(Deduplicate(deliveries))
+	service.registerEvents()
+
+	return service
 }
 
-func (service *MediatorService) Mediate(event string, payload []byte) (err error) {
+func (service *MediatorService) registerEvents() {
+	pullRequestHandler := handlePayload(service.handlePullRequestPayload)
+	for _, action := range []string{
+		"assigned", "unassigned", "review_requested", "review_request_removed", "labeled", "unlabeled",
+		"opened", "edited", "closed", "reopened", "synchronize", "ready_for_review",
+	} {
+		service.HandleAction("pull_request", action, pullRequestHandler)
+	}
 
-	switch event {
-	case "pull_request":
-		err = service.handlePullRequestPayload(payload)
-	case "member":
-		err = service.handleMemberPayload(payload)
+	for _, action := range []string{"submitted", "edited", "dismissed"} {
+		service.HandleAction("pull_request_review", action, handlePayload(service.handlePullRequestReviewPayload))
 	}
 
-	if err != nil {
-		if err = service.payloads.Save(string(payload)); err != nil {
+	for _, action := range []string{"created", "edited", "deleted"} {
+		service.HandleAction("pull_request_review_comment", action, handlePayload(service.handlePullRequestReviewCommentPayload))
+		service.HandleAction("issue_comment", action, handlePayload(service.handleIssueCommentPayload))
+	}
+
+	for _, action := range []string{"added", "edited", "deleted"} {
+		service.HandleAction("member", action, handlePayload(service.handleMemberPayload))
+	}
+
+	service.HandleAction("ping", "", handlePayload(service.handlePingPayload))
+}
+
+// Handle registers handler for all actions of event, handlers registered for
+// particular actions with HandleAction take precedence. Handlers must be registered
+// before the first event is mediated.
+func (service *MediatorService) Handle(event string, handler Handler) {
+	service.events.register(event, anyAction, handler)
+}
+
+// HandleAction registers handler for the action of event, use an empty action
+// for events that carry none.
+func (service *MediatorService) HandleAction(event, action string, handler Handler) {
+	service.events.register(event, action, handler)
+}
+
+// Use adds middleware wrapping every handler. Middlewares added first are
+// called first.
+func (service *MediatorService) Use(middleware ...Middleware) {
+	service.middleware = append(service.middleware, middleware...)
+}
+
+func handlePayload(fn func(ctx context.Context, payload []byte) error) Handler {
+	return HandlerFunc(func(ctx context.Context, event *Event) error {
+		return fn(ctx, event.Payload)
+	})
+}
+
+// Mediate handles the payload of given event. Deliveries that have already been
+// processed are skipped, an empty deliveryID disables this check. Payloads of
+// deliveries that failed to be handled are saved for retry, which is reported
+// with SavedForRetryError.
+func (service *MediatorService) Mediate(ctx context.Context, event, deliveryID string, payload []byte) (err error) {
+	if err = service.handle(ctx, event, deliveryID, payload); err != nil {
+		if isPermanent(err) {
 			return err
 		}
-		return err
+
+		now := time.Now()
+
+		envelope := &Envelope{
+			Event:         event,
+			DeliveryID:    deliveryID,
+			Repository:    repositoryName(payload),
+			Payload:       string(payload),
+			ReceivedAt:    now,
+			LastError:     err.Error(),
+			Attempts:      1,
+			LastAttemptAt: now,
+		}
+
+		if saveErr := service.payloads.Save(envelope); saveErr != nil {
+			return saveErr
+		}
+
+		return &SavedForRetryError{err}
 	}
 
 	return nil
 }
 
-func (service *MediatorService) handleMemberPayload(payload []byte) (err error) {
+// Supports reports whether there is a handler registered for event and action,
+// an empty action matches any action of the event.
+func (service *MediatorService) Supports(event, action string) bool {
+	if action == "" {
+		return len(service.events[event]) > 0
+	}
+
+	_, err := service.events.lookup(event, action)
+	return err == nil
+}
+
+// Replay handles the payload of saved envelope, unlike Mediate it does not save
+// the payload again if handling fails.
+func (service *MediatorService) Replay(ctx context.Context, envelope *Envelope) error {
+	return service.handle(ctx, envelope.Event, envelope.DeliveryID, []byte(envelope.Payload))
+}
+
+func (service *MediatorService) handle(ctx context.Context, name, deliveryID string, payload []byte) (err error) {
+	action, err := PayloadAction(payload)
+	if err != nil {
+		return err
+	}
+
+	handler, err := service.events.lookup(name, action)
+	if err != nil {
+		return err
+	}
+
+	ctx = logging.NewContext(ctx, logging.FromContext(ctx).WithFields(deliveryFields(name, action, deliveryID, payload)))
+
+	for i := len(service.middleware) - 1; i >= 0; i-- {
+		handler = service.middleware[i](handler)
+	}
+
+	return handler.Handle(ctx, &Event{Name: name, Action: action, DeliveryID: deliveryID, Payload: payload})
+}
+
+func (service *MediatorService) handlePullRequestReviewPayload(ctx context.Context, payload []byte) (err error) {
+	ghReviewHook := new(gh.GithubPullRequestReviewHook)
+
+	if err = json.Unmarshal(payload, &ghReviewHook); err != nil {
+		return err
+	}
+
+	return service.webClient.ProcessReview(ctx, bw.NewReviewFromGithubHook(ghReviewHook))
+}
+
+func (service *MediatorService) handlePullRequestReviewCommentPayload(ctx context.Context, payload []byte) (err error) {
+	ghCommentHook := new(gh.GithubPullRequestReviewCommentHook)
+
+	if err = json.Unmarshal(payload, &ghCommentHook); err != nil {
+		return err
+	}
+
+	return service.webClient.ProcessReviewComment(ctx, bw.NewReviewCommentFromGithubHook(ghCommentHook))
+}
+
+func (service *MediatorService) handleIssueCommentPayload(ctx context.Context, payload []byte) (err error) {
+	ghCommentHook := new(gh.GithubIssueCommentHook)
+
+	if err = json.Unmarshal(payload, &ghCommentHook); err != nil {
+		return err
+	}
+
+	// comments on plain issues are of no interest
+	if !ghCommentHook.Issue.IsPullRequest() {
+		return nil
+	}
+
+	return service.webClient.ProcessComment(ctx, bw.NewCommentFromGithubHook(ghCommentHook))
+}
+
+// handlePingPayload confirms tracking of repository once GitHub pings the hook
+// created for it.
+func (service *MediatorService) handlePingPayload(ctx context.Context, payload []byte) (err error) {
+	ghPingHook := new(gh.GithubPingHook)
+
+	if err = json.Unmarshal(payload, &ghPingHook); err != nil {
+		return err
+	}
+
+	if !isValidHook(ghPingHook) {
+		return ErrInvalidHook
+	}
+
+	return service.trackings.Confirm(ghPingHook.Repository.FullName, ghPingHook.HookId)
+}
+
+func isValidHook(ghPingHook *gh.GithubPingHook) bool {
+	hook := ghPingHook.Hook
+
+	if ghPingHook.HookId == 0 || hook.GetID() != ghPingHook.HookId || ghPingHook.Repository.FullName == "" {
+		return false
+	}
+
+	if url, _ := hook.Config["url"].(string); url == "" {
+		return false
+	}
+
+	if contentType, _ := hook.Config["content_type"].(string); contentType != "json" {
+		return false
+	}
+
+	for _, event := range hook.Events {
+		if event == "pull_request" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (service *MediatorService) handleMemberPayload(ctx context.Context, payload []byte) (err error) {
 	ghMemberHook := new(gh.GithubMemberHook)
 
 	if err = json.Unmarshal(payload, &ghMemberHook); err != nil {
@@ -89,16 +301,16 @@ func (service *MediatorService) handleMemberPayload(payload []byte) (err error)
 
 	switch ghMemberHook.Action {
 	case "added":
-		err = service.collaboratorsClient.AddCollaborator(memberRepositoryName, &member)
+		err = service.collaboratorsClient.AddCollaborator(ctx, memberRepositoryName, &member)
 	case "edited":
-		err = service.collaboratorsClient.EditCollaborator(memberRepositoryName, &member)
+		err = service.collaboratorsClient.EditCollaborator(ctx, memberRepositoryName, &member)
 	case "deleted":
-		err = service.collaboratorsClient.DeleteCollaborator(memberRepositoryName, ghMemberHook.Member.Login)
+		err = service.collaboratorsClient.DeleteCollaborator(ctx, memberRepositoryName, ghMemberHook.Member.Login)
 	}
 	return err
 }
 
-func (service *MediatorService) handlePullRequestPayload(payload []byte) (err error) {
+func (service *MediatorService) handlePullRequestPayload(ctx context.Context, payload []byte) (err error) {
 	ghPullRequestHook := new(gh.GithubPullRequestHook)
 	if err = json.Unmarshal(payload, &ghPullRequestHook); err != nil {
 		return err
@@ -108,25 +320,40 @@ func (service *MediatorService) handlePullRequestPayload(payload []byte) (err er
 
 	pullRequest := bw.NewPullRequestFromGithubHook(ghPullRequestHook)
 
-	if len(pullRequest.Reviewers) == 0 {
-		listCollaborators, err := service.collaboratorsClient.ListCollaborator(hookRepositoryName)
+	policy := service.policies.For(hookRepositoryName)
 
-		reviewer := service.pickCollaboratorFrom(listCollaborators)
-		reviewers := []gh.Collaborator{*reviewer}
+	if assignmentActions[ghPullRequestHook.Action] {
+		requiredReviewers := policy.RequiredReviewers
+		if requiredReviewers < 1 {
+			requiredReviewers = 1
+		}
 
-		pullRequest.Reviewers = reviewers
+		outcome, err := service.assignReviewers(ctx, pullRequest, requiredReviewers-len(pullRequest.Reviewers))
+		if service.ReviewerAssignments != nil {
+			service.ReviewerAssignments.With(outcome).Inc()
+		}
 
-		if err = service.reviewersClient.RequestReviewers(gh.Context{},
-			hookRepositoryName,
-			pullRequest.Number,
-			reviewers,
-		); err != nil {
+		if err != nil {
 			return err
 		}
+
+		if policy.Team != "" && !isTeamRequested(pullRequest, policy.Team) {
+			if err = service.reviewersClient.RequestTeamReviewers(gh.Context{Context: ctx},
+				hookRepositoryName,
+				pullRequest.Number,
+				[]string{policy.Team},
+			); err != nil {
+				return err
+			}
+
+			pullRequest.RequestedTeams = append(pullRequest.RequestedTeams, gh.Team{Slug: policy.Team})
+		}
 	}
 
+	// review comments are streamed as they happen, once pull request is closed
+	// they are fetched in bulk to reconcile those missed
 	if pullRequest.State != "open" {
-		comments, err := service.reviewersClient.ReviewComments(gh.Context{},
+		comments, err := service.reviewersClient.ReviewComments(gh.Context{Context: ctx},
 			hookRepositoryName,
 			pullRequest.Number,
 		)
@@ -137,7 +364,7 @@ func (service *MediatorService) handlePullRequestPayload(payload []byte) (err er
 		pullRequest.ReviewComments = comments
 	}
 
-	err = service.webClient.ProcessPullRequest(pullRequest)
+	err = service.webClient.ProcessPullRequest(ctx, pullRequest)
 
 	if err != nil {
 		return err
@@ -146,19 +373,156 @@ func (service *MediatorService) handlePullRequestPayload(payload []byte) (err er
 	return nil
 }
 
-func (service *MediatorService) pickCollaboratorFrom(collaborators []gh.Collaborator) *gh.Collaborator {
-	admins := make([]gh.Collaborator, 0)
+// assignmentActions are pull request actions reviewers and teams are requested on.
+// GitHub removes reviewers and teams from requested ones once they submit a review,
+// so topping them up on any later action would request them again after each review.
+var assignmentActions = map[string]bool{
+	"opened":           true,
+	"reopened":         true,
+	"ready_for_review": true,
+}
+
+// assignReviewers requests missing reviewers for pull request and reports the outcome
+// of assignment, one of "assigned", "satisfied", "no_eligible_reviewer" or "failed".
+func (service *MediatorService) assignReviewers(ctx context.Context, pullRequest *bw.PullRequest, missing int) (string, error) {
+	if missing <= 0 {
+		return "satisfied", nil
+	}
+
+	listCollaborators, err := service.collaboratorsClient.ListCollaborator(ctx, pullRequest.RepositoryName)
+	if err != nil {
+		return "failed", err
+	}
+
+	reviewers, err := service.reviewerPicker.Pick(ctx, pullRequest, eligibleReviewers(pullRequest, listCollaborators), missing)
+
+	switch {
+	case err == ErrNoEligibleReviewer:
+		// the pull request is still passed on to the web service, e.g. when its
+		// author is the only collaborator
+		logging.FromContext(ctx).With("reviewers", len(pullRequest.Reviewers)).Warnf("found no eligible reviewer")
+		return "no_eligible_reviewer", nil
+	case err != nil:
+		return "failed", err
+	}
+
+	if err = service.reviewersClient.RequestReviewers(gh.Context{Context: ctx},
+		pullRequest.RepositoryName,
+		pullRequest.Number,
+		reviewers,
+	); err != nil {
+		return "failed", err
+	}
+
+	pullRequest.Reviewers = append(pullRequest.Reviewers, reviewers...)
+
+	return "assigned", nil
+}
+
+// eligibleReviewers excludes pull request author and already requested reviewers from collaborators
+func eligibleReviewers(pullRequest *bw.PullRequest, collaborators []gh.Collaborator) []gh.Collaborator {
+	eligible := make([]gh.Collaborator, 0, len(collaborators))
 
 	for _, collaborator := range collaborators {
-		if collaborator.Admin {
-			admins = append(admins, collaborator)
+		if collaborator.Id == pullRequest.OwnerId || collaborator.Login == pullRequest.OwnerLogin {
+			continue
 		}
+
+		if isRequested(pullRequest, collaborator) {
+			continue
+		}
+
+		eligible = append(eligible, collaborator)
 	}
 
-	rand.Seed(time.Now().Unix())
+	return eligible
+}
 
-	n := rand.Int() % len(admins)
+// isPermanent reports whether handling failed for a reason retries will not fix.
+func isPermanent(err error) bool {
+	if _, ok := err.(*UnsupportedEventError); ok {
+		return true
+	}
 
-	return &admins[n]
-	return nil
+	return err == ErrNoEligibleReviewer || err == ErrInvalidHook
+}
+
+func isRequested(pullRequest *bw.PullRequest, collaborator gh.Collaborator) bool {
+	for _, reviewer := range pullRequest.Reviewers {
+		if reviewer.Id == collaborator.Id || reviewer.Login == collaborator.Login {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isTeamRequested(pullRequest *bw.PullRequest, slug string) bool {
+	for _, team := range pullRequest.RequestedTeams {
+		if strings.EqualFold(team.Slug, slug) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pullRequestNumber returns the number of pull request or issue the payload is
+// about or 0 if there is none. Pull requests share numbers with issues, comments on
+// them come as issue_comment.
+func pullRequestNumber(payload []byte) int {
+	hook := new(struct {
+		PullRequest *struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+		Issue *struct {
+			Number int `json:"number"`
+		} `json:"issue"`
+	})
+
+	if err := json.Unmarshal(payload, hook); err != nil {
+		return 0
+	}
+
+	switch {
+	case hook.PullRequest != nil:
+		return hook.PullRequest.Number
+	case hook.Issue != nil:
+		return hook.Issue.Number
+	}
+
+	return 0
+}
+
+// deliveryFields returns log fields identifying the delivery
+func deliveryFields(event, action, deliveryID string, payload []byte) logging.Fields {
+	fields := logging.Fields{
+		"event":       event,
+		"delivery_id": deliveryID,
+		"repository":  repositoryName(payload),
+	}
+
+	if action != "" {
+		fields["action"] = action
+	}
+
+	if number := pullRequestNumber(payload); number > 0 {
+		fields["pull_request"] = number
+	}
+
+	return fields
+}
+
+func repositoryName(payload []byte) string {
+	hook := new(struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	})
+
+	if err := json.Unmarshal(payload, hook); err != nil {
+		return ""
+	}
+
+	return hook.Repository.FullName
 }
@@ -16,33 +16,49 @@
 package hooks_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/blamewarrior/hooks"
 	bw "github.com/blamewarrior/hooks/blamewarrior"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	gh "github.com/blamewarrior/hooks/github"
+	"github.com/blamewarrior/hooks/logging"
+	"github.com/blamewarrior/hooks/metrics"
 )
 
 type PayloadServiceMock struct {
 	mock.Mock
 }
 
-func (m *PayloadServiceMock) Save(payload string) (err error) {
-	args := m.Called()
+func (m *PayloadServiceMock) Save(envelope *hooks.Envelope) (err error) {
+	args := m.Called(envelope)
 	return args.Error(0)
 }
 
-func (m *PayloadServiceMock) List(limit int64) (result []string, err error) {
+func (m *PayloadServiceMock) List(limit int64) (result []*hooks.Envelope, err error) {
 	return
 }
 
-func (m *PayloadServiceMock) Delete(payload string) (err error) {
+func (m *PayloadServiceMock) Rotate(limit int64) (result []*hooks.Envelope, err error) {
+	return
+}
+
+func (m *PayloadServiceMock) Delete(envelope *hooks.Envelope) (err error) {
+	return
+}
+
+func (m *PayloadServiceMock) Bury(envelope *hooks.Envelope) (err error) {
 	return
 }
 
@@ -50,36 +66,73 @@ type WebClientMock struct {
 	mock.Mock
 }
 
-func (m *WebClientMock) ProcessPullRequest(pullRequest *bw.PullRequest) (err error) {
+func (m *WebClientMock) ProcessPullRequest(ctx context.Context, pullRequest *bw.PullRequest) (err error) {
 	args := m.Called(pullRequest)
 	return args.Error(0)
 }
 
+func (m *WebClientMock) ProcessReview(ctx context.Context, review *bw.Review) (err error) {
+	args := m.Called(review)
+	return args.Error(0)
+}
+
+func (m *WebClientMock) ProcessReviewComment(ctx context.Context, comment *bw.ReviewComment) (err error) {
+	args := m.Called(comment)
+	return args.Error(0)
+}
+
+func (m *WebClientMock) ProcessComment(ctx context.Context, comment *bw.Comment) (err error) {
+	args := m.Called(comment)
+	return args.Error(0)
+}
+
+type TrackingsMock struct {
+	mock.Mock
+}
+
+func (m *TrackingsMock) Confirm(repoFullName string, hookID int) error {
+	args := m.Called(repoFullName, hookID)
+	return args.Error(0)
+}
+
+func (m *TrackingsMock) Get(repoFullName string) (*hooks.Tracking, error) {
+	args := m.Called(repoFullName)
+
+	tracking, _ := args.Get(0).(*hooks.Tracking)
+
+	return tracking, args.Error(1)
+}
+
+func (m *TrackingsMock) Delete(repoFullName string) error {
+	args := m.Called(repoFullName)
+	return args.Error(0)
+}
+
 type CollaboratorsClientMock struct {
 	mock.Mock
 }
 
-func (m *CollaboratorsClientMock) FetchCollaborators(repositoryFullName string) error {
+func (m *CollaboratorsClientMock) FetchCollaborators(ctx context.Context, repositoryFullName string) error {
 	args := m.Called(repositoryFullName)
 	return args.Error(0)
 }
 
-func (m *CollaboratorsClientMock) ListCollaborator(repositoryFullName string) ([]gh.Collaborator, error) {
+func (m *CollaboratorsClientMock) ListCollaborator(ctx context.Context, repositoryFullName string) ([]gh.Collaborator, error) {
 	args := m.Called(repositoryFullName)
 	return args.Get(0).([]gh.Collaborator), args.Error(1)
 }
 
-func (m *CollaboratorsClientMock) AddCollaborator(repositoryFullName string, collaborator *gh.Collaborator) error {
+func (m *CollaboratorsClientMock) AddCollaborator(ctx context.Context, repositoryFullName string, collaborator *gh.Collaborator) error {
 	args := m.Called(repositoryFullName, collaborator)
 	return args.Error(0)
 }
 
-func (m *CollaboratorsClientMock) EditCollaborator(repositoryFullName string, collaborator *gh.Collaborator) error {
+func (m *CollaboratorsClientMock) EditCollaborator(ctx context.Context, repositoryFullName string, collaborator *gh.Collaborator) error {
 	args := m.Called(repositoryFullName, collaborator)
 	return args.Error(0)
 }
 
-func (m *CollaboratorsClientMock) DeleteCollaborator(repositoryFullName, login string) error {
+func (m *CollaboratorsClientMock) DeleteCollaborator(ctx context.Context, repositoryFullName, login string) error {
 	args := m.Called(repositoryFullName, login)
 	return args.Error(0)
 }
@@ -93,6 +146,16 @@ func (m *ReviewersServiceMock) RequestReviewers(ctx gh.Context, repoFullName str
 	return args.Error(0)
 }
 
+func (m *ReviewersServiceMock) RequestTeamReviewers(ctx gh.Context, repoFullName string, pullNumber int, teamSlugs []string) (err error) {
+	args := m.Called(ctx, repoFullName, pullNumber, teamSlugs)
+	return args.Error(0)
+}
+
+func (m *ReviewersServiceMock) RemoveTeamReviewers(ctx gh.Context, repoFullName string, pullNumber int, teamSlugs []string) (err error) {
+	args := m.Called(ctx, repoFullName, pullNumber, teamSlugs)
+	return args.Error(0)
+}
+
 func (m *ReviewersServiceMock) ReviewComments(ctx gh.Context, repoFullName string, pullNumber int) ([]gh.ReviewComment, error) {
 	args := m.Called(ctx, repoFullName, pullNumber)
 	return args.Get(0).([]gh.ReviewComment), args.Error(1)
@@ -122,21 +185,22 @@ func TestHooksMediator_Mediate_HooksWithAssignedReviewers(t *testing.T) {
 		Reviewers: []gh.Collaborator{gh.Collaborator{Id: 6752318,
 			Login: "blamewarrior_second_user",
 			Admin: false}},
-		Number:    1,
-		State:     "open",
-		CreatedAt: &createdAt,
-		OwnerId:   6752317,
-		Commits:   1,
-		Additions: 1,
-		Deletions: 1,
+		Number:     1,
+		State:      "open",
+		CreatedAt:  &createdAt,
+		OwnerId:    6752317,
+		OwnerLogin: "blamewarrior_user",
+		Commits:    1,
+		Additions:  1,
+		Deletions:  1,
 	}
 
 	webClientMock.On("ProcessPullRequest", pullRequest).Return(nil)
 
 	reviewersService := new(ReviewersServiceMock)
 
-	m := hooks.NewMediatorService(payloadServiceMock, webClientMock, collaboratorsClientMock, reviewersService)
-	m.Mediate("pull_request", []byte(pullRequestHookPayloadWithAssignedReviewers))
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+	m.Mediate(context.Background(), "pull_request", "", []byte(pullRequestHookPayloadWithAssignedReviewers))
 
 	webClientMock.AssertExpectations(t)
 
@@ -178,30 +242,389 @@ func TestHooksMediator_Mediate_HooksWithoutAssignedReviewers(t *testing.T) {
 			Login: "admin_user",
 			Admin: true},
 		},
-		Number:    1,
-		State:     "open",
-		CreatedAt: &createdAt,
-		OwnerId:   6752317,
-		Commits:   1,
-		Additions: 1,
-		Deletions: 1,
+		Number:     1,
+		State:      "open",
+		CreatedAt:  &createdAt,
+		OwnerId:    6752317,
+		OwnerLogin: "blamewarrior_user",
+		Commits:    1,
+		Additions:  1,
+		Deletions:  1,
 	}
 
 	webClientMock.On("ProcessPullRequest", pullRequest).Return(nil)
 
 	reviewersService := new(ReviewersServiceMock)
 	reviewersService.On("RequestReviewers",
-		gh.Context{},
+		mock.AnythingOfType("github.Context"),
 		"blamewarrior_user/public-repo",
 		1,
 		collaborators,
 	).Return(nil)
 
-	m := hooks.NewMediatorService(payloadServiceMock, webClientMock, collaboratorsClientMock, reviewersService)
-	m.Mediate("pull_request", []byte(pullRequestHookPayloadWithoutAssignedReviewers))
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+	m.Mediate(context.Background(), "pull_request", "", []byte(pullRequestHookPayloadWithoutAssignedReviewers))
+
+	reviewersService.AssertExpectations(t)
+
+}
+
+func TestHooksMediator_Mediate_AuthorIsNotReviewer(t *testing.T) {
+
+	collaborators := []gh.Collaborator{
+		{
+			Id:    6752317,
+			Login: "blamewarrior_user",
+			Admin: true,
+		},
+		{
+			Id:    6752318,
+			Login: "blamewarrior_second_user",
+			Admin: false,
+		},
+	}
+
+	payloadServiceMock := new(PayloadServiceMock)
+
+	collaboratorsClientMock := new(CollaboratorsClientMock)
+
+	collaboratorsClientMock.On("ListCollaborator", "blamewarrior_user/public-repo").Return(collaborators, nil)
+
+	webClientMock := new(WebClientMock)
+
+	webClientMock.On("ProcessPullRequest", mock.AnythingOfType("*blamewarrior.PullRequest")).Return(nil)
+
+	reviewersService := new(ReviewersServiceMock)
+	reviewersService.On("RequestReviewers",
+		mock.AnythingOfType("github.Context"),
+		"blamewarrior_user/public-repo",
+		1,
+		collaborators[1:],
+	).Return(nil)
+
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+	err := m.Mediate(context.Background(), "pull_request", "", []byte(pullRequestHookPayloadWithoutAssignedReviewers))
+	require.NoError(t, err)
+
+	reviewersService.AssertExpectations(t)
+}
+
+func TestHooksMediator_Mediate_NoEligibleReviewers(t *testing.T) {
+
+	collaborators := []gh.Collaborator{
+		{
+			Id:    6752317,
+			Login: "blamewarrior_user",
+			Admin: true,
+		},
+	}
+
+	payloadServiceMock := new(PayloadServiceMock)
+
+	collaboratorsClientMock := new(CollaboratorsClientMock)
+
+	collaboratorsClientMock.On("ListCollaborator", "blamewarrior_user/public-repo").Return(collaborators, nil)
+
+	webClientMock := new(WebClientMock)
+	webClientMock.On("ProcessPullRequest", mock.AnythingOfType("*blamewarrior.PullRequest")).Return(nil)
+
+	reviewersService := new(ReviewersServiceMock)
+
+	registry := metrics.NewRegistry()
+
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+	m.ReviewerAssignments = registry.Counter("reviewer_assignments_total", "Reviewer assignments.", "outcome")
+
+	err := m.Mediate(context.Background(), "pull_request", "", []byte(pullRequestHookPayloadWithoutAssignedReviewers))
+	require.NoError(t, err)
+
+	reviewersService.AssertNotCalled(t, "RequestReviewers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	// the pull request still reaches the web service
+	webClientMock.AssertNumberOfCalls(t, "ProcessPullRequest", 1)
+	payloadServiceMock.AssertNotCalled(t, "Save", mock.Anything)
+
+	var buf bytes.Buffer
+	_, err = registry.WriteTo(&buf)
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), `reviewer_assignments_total{outcome="no_eligible_reviewer"} 1`)
+}
+
+func TestHooksMediator_Mediate_ReviewerAssignments(t *testing.T) {
+	collaborators := []gh.Collaborator{
+		{
+			Id:    123,
+			Login: "admin_user",
+			Admin: true,
+		},
+	}
+
+	collaboratorsClientMock := new(CollaboratorsClientMock)
+	collaboratorsClientMock.On("ListCollaborator", "blamewarrior_user/public-repo").Return(collaborators, nil)
+
+	webClientMock := new(WebClientMock)
+	webClientMock.On("ProcessPullRequest", mock.Anything).Return(nil)
+
+	reviewersService := new(ReviewersServiceMock)
+	reviewersService.On("RequestReviewers", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	registry := metrics.NewRegistry()
+
+	m := hooks.NewMediatorService(new(PayloadServiceMock), hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+	m.ReviewerAssignments = registry.Counter("reviewer_assignments_total", "Reviewer assignments.", "outcome")
+
+	require.NoError(t, m.Mediate(context.Background(), "pull_request", "", []byte(pullRequestHookPayloadWithoutAssignedReviewers)))
+	require.NoError(t, m.Mediate(context.Background(), "pull_request", "", []byte(pullRequestHookPayloadWithAssignedReviewers)))
+
+	var buf bytes.Buffer
+	_, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), `reviewer_assignments_total{outcome="assigned"} 1`)
+	assert.Contains(t, buf.String(), `reviewer_assignments_total{outcome="satisfied"} 1`)
+}
+
+func TestHooksMediator_Mediate_RequiredReviewers(t *testing.T) {
+
+	collaborators := []gh.Collaborator{
+		{
+			Id:    6752317,
+			Login: "blamewarrior_user",
+			Admin: true,
+		},
+		{
+			Id:    6752318,
+			Login: "blamewarrior_second_user",
+			Admin: false,
+		},
+		{
+			Id:    6752319,
+			Login: "blamewarrior_third_user",
+			Admin: false,
+		},
+		{
+			Id:    6752320,
+			Login: "blamewarrior_fourth_user",
+			Admin: true,
+		},
+	}
+
+	results := []struct {
+		Payload            string
+		RequiredReviewers  int
+		RequestedReviewers []gh.Collaborator
+		Reviewers          []gh.Collaborator
+	}{
+		{
+			Payload:            pullRequestHookPayloadWithoutAssignedReviewers,
+			RequiredReviewers:  2,
+			RequestedReviewers: []gh.Collaborator{collaborators[3], collaborators[1]},
+			Reviewers:          []gh.Collaborator{collaborators[3], collaborators[1]},
+		},
+		{
+			Payload:            pullRequestHookPayloadWithAssignedReviewers,
+			RequiredReviewers:  2,
+			RequestedReviewers: []gh.Collaborator{collaborators[3]},
+			Reviewers:          []gh.Collaborator{collaborators[1], collaborators[3]},
+		},
+		{
+			Payload:            pullRequestHookPayloadWithAssignedReviewers,
+			RequiredReviewers:  5,
+			RequestedReviewers: []gh.Collaborator{collaborators[3], collaborators[2]},
+			Reviewers:          []gh.Collaborator{collaborators[1], collaborators[3], collaborators[2]},
+		},
+	}
+
+	for _, result := range results {
+		payloadServiceMock := new(PayloadServiceMock)
+
+		collaboratorsClientMock := new(CollaboratorsClientMock)
+
+		collaboratorsClientMock.On("ListCollaborator", "blamewarrior_user/public-repo").Return(collaborators, nil)
+
+		webClientMock := new(WebClientMock)
+
+		webClientMock.On("ProcessPullRequest", mock.MatchedBy(func(pullRequest *bw.PullRequest) bool {
+			return assert.Equal(t, result.Reviewers, pullRequest.Reviewers)
+		})).Return(nil)
+
+		reviewersService := new(ReviewersServiceMock)
+		reviewersService.On("RequestReviewers",
+			mock.AnythingOfType("github.Context"),
+			"blamewarrior_user/public-repo",
+			1,
+			result.RequestedReviewers,
+		).Return(nil)
+
+		policies := hooks.NewPolicies(
+			hooks.Policy{RequiredReviewers: 1},
+			map[string]hooks.Policy{"blamewarrior_user/public-repo": {RequiredReviewers: result.RequiredReviewers}},
+		)
+
+		m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRoundRobinPicker(), policies)
+
+		err := m.Mediate(context.Background(), "pull_request", "", []byte(result.Payload))
+		require.NoError(t, err)
+
+		reviewersService.AssertExpectations(t)
+		webClientMock.AssertExpectations(t)
+	}
+}
+
+func TestHooksMediator_Mediate_NoReviewersToTopUp(t *testing.T) {
+
+	collaborators := []gh.Collaborator{
+		{
+			Id:    6752317,
+			Login: "blamewarrior_user",
+			Admin: true,
+		},
+		{
+			Id:    6752318,
+			Login: "blamewarrior_second_user",
+			Admin: false,
+		},
+	}
+
+	payloadServiceMock := new(PayloadServiceMock)
+
+	collaboratorsClientMock := new(CollaboratorsClientMock)
+
+	collaboratorsClientMock.On("ListCollaborator", "blamewarrior_user/public-repo").Return(collaborators, nil)
+
+	webClientMock := new(WebClientMock)
+
+	webClientMock.On("ProcessPullRequest", mock.AnythingOfType("*blamewarrior.PullRequest")).Return(nil)
+
+	reviewersService := new(ReviewersServiceMock)
+
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 2}, nil))
+
+	err := m.Mediate(context.Background(), "pull_request", "", []byte(pullRequestHookPayloadWithAssignedReviewers))
+	require.NoError(t, err)
+
+	reviewersService.AssertNotCalled(t, "RequestReviewers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	webClientMock.AssertExpectations(t)
+}
+
+func TestHooksMediator_Mediate_NoTopUpOnLaterActions(t *testing.T) {
+	results := []struct {
+		Action  string
+		Payload string
+	}{
+		{"review_request_removed", strings.Replace(pullRequestHookPayloadWithAssignedReviewers, `"action": "opened"`, `"action": "review_request_removed"`, 1)},
+		{"synchronize", strings.Replace(pullRequestHookPayloadWithoutAssignedReviewers, `"action": "opened"`, `"action": "synchronize"`, 1)},
+		{"closed", closedPullRequestHookPayload},
+	}
+
+	for _, result := range results {
+		payloadServiceMock := new(PayloadServiceMock)
+
+		collaboratorsClientMock := new(CollaboratorsClientMock)
+
+		webClientMock := new(WebClientMock)
+
+		webClientMock.On("ProcessPullRequest", mock.AnythingOfType("*blamewarrior.PullRequest")).Return(nil)
+
+		reviewersService := new(ReviewersServiceMock)
+		reviewersService.On("ReviewComments", mock.AnythingOfType("github.Context"), "blamewarrior_user/public-repo", 1).Return([]gh.ReviewComment{}, nil)
+
+		m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 2}, nil))
+
+		err := m.Mediate(context.Background(), "pull_request", "", []byte(result.Payload))
+		require.NoError(t, err, result.Action)
+
+		collaboratorsClientMock.AssertNotCalled(t, "ListCollaborator", mock.Anything)
+		reviewersService.AssertNotCalled(t, "RequestReviewers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		webClientMock.AssertExpectations(t)
+	}
+}
+
+func TestHooksMediator_Mediate_TeamReviewers(t *testing.T) {
+	payloadServiceMock := new(PayloadServiceMock)
+
+	collaboratorsClientMock := new(CollaboratorsClientMock)
+
+	webClientMock := new(WebClientMock)
+
+	webClientMock.On("ProcessPullRequest", mock.MatchedBy(func(pullRequest *bw.PullRequest) bool {
+		return assert.Equal(t, []gh.Team{{Slug: "core"}}, pullRequest.RequestedTeams)
+	})).Return(nil)
+
+	reviewersService := new(ReviewersServiceMock)
+
+	reviewersService.On("RequestTeamReviewers", mock.Anything, "blamewarrior_user/public-repo", 1, []string{"core"}).Return(nil)
+
+	policies := hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, map[string]hooks.Policy{
+		"blamewarrior_user/public-repo": {RequiredReviewers: 1, Team: "core"},
+	})
+
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), policies)
+
+	err := m.Mediate(context.Background(), "pull_request", "", []byte(pullRequestHookPayloadWithAssignedReviewers))
+	require.NoError(t, err)
 
 	reviewersService.AssertExpectations(t)
+	webClientMock.AssertExpectations(t)
+}
+
+func TestHooksMediator_Mediate_TeamAlreadyRequested(t *testing.T) {
+	payload := strings.Replace(
+		pullRequestHookPayloadWithAssignedReviewers,
+		`"requested_reviewers": [{`,
+		`"requested_teams": [{"id": 2274583, "slug": "core", "name": "Core"}],
+    "requested_reviewers": [{`,
+		1,
+	)
+
+	payloadServiceMock := new(PayloadServiceMock)
+
+	collaboratorsClientMock := new(CollaboratorsClientMock)
+
+	webClientMock := new(WebClientMock)
+
+	webClientMock.On("ProcessPullRequest", mock.MatchedBy(func(pullRequest *bw.PullRequest) bool {
+		return assert.Equal(t, []gh.Team{{Id: 2274583, Slug: "core", Name: "Core"}}, pullRequest.RequestedTeams)
+	})).Return(nil)
+
+	reviewersService := new(ReviewersServiceMock)
+
+	policies := hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, map[string]hooks.Policy{
+		"blamewarrior_user/public-repo": {RequiredReviewers: 1, Team: "core"},
+	})
+
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), policies)
+
+	err := m.Mediate(context.Background(), "pull_request", "", []byte(payload))
+	require.NoError(t, err)
+
+	reviewersService.AssertNotCalled(t, "RequestTeamReviewers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	webClientMock.AssertExpectations(t)
+}
+
+func TestHooksMediator_Mediate_NoTeamRequestOnLaterActions(t *testing.T) {
+	payload := strings.Replace(pullRequestHookPayloadWithAssignedReviewers, `"action": "opened"`, `"action": "synchronize"`, 1)
+
+	payloadServiceMock := new(PayloadServiceMock)
+
+	webClientMock := new(WebClientMock)
+
+	webClientMock.On("ProcessPullRequest", mock.AnythingOfType("*blamewarrior.PullRequest")).Return(nil)
+
+	reviewersService := new(ReviewersServiceMock)
+
+	policies := hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, map[string]hooks.Policy{
+		"blamewarrior_user/public-repo": {RequiredReviewers: 1, Team: "core"},
+	})
 
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, new(CollaboratorsClientMock), reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), policies)
+
+	err := m.Mediate(context.Background(), "pull_request", "", []byte(payload))
+	require.NoError(t, err)
+
+	reviewersService.AssertNotCalled(t, "RequestTeamReviewers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	webClientMock.AssertExpectations(t)
 }
 
 func TestHooksMediator_Mediate_ClosedPullRequest(t *testing.T) {
@@ -236,6 +659,7 @@ func TestHooksMediator_Mediate_ClosedPullRequest(t *testing.T) {
 		State:          "closed",
 		CreatedAt:      &createdAt,
 		OwnerId:        6752317,
+		OwnerLogin:     "blamewarrior_user",
 		Commits:        1,
 		Additions:      1,
 		Deletions:      1,
@@ -245,10 +669,10 @@ func TestHooksMediator_Mediate_ClosedPullRequest(t *testing.T) {
 	webClientMock.On("ProcessPullRequest", pullRequest).Return(nil)
 
 	reviewersService := new(ReviewersServiceMock)
-	reviewersService.On("ReviewComments", gh.Context{}, "blamewarrior_user/public-repo", 1).Return(comments[0:], nil)
+	reviewersService.On("ReviewComments", mock.AnythingOfType("github.Context"), "blamewarrior_user/public-repo", 1).Return(comments[0:], nil)
 
-	m := hooks.NewMediatorService(payloadServiceMock, webClientMock, collaboratorsClientMock, reviewersService)
-	m.Mediate("pull_request", []byte(closedPullRequestHookPayload))
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+	m.Mediate(context.Background(), "pull_request", "", []byte(closedPullRequestHookPayload))
 
 	reviewersService.AssertExpectations(t)
 }
@@ -273,8 +697,8 @@ func TestMediator_Mediate_AddColaborator(t *testing.T) {
 
 	reviewersService := new(ReviewersServiceMock)
 
-	m := hooks.NewMediatorService(payloadServiceMock, webClientMock, collaboratorsClientMock, reviewersService)
-	m.Mediate("member", []byte(fmt.Sprintf(pullRequestPayloadWithMember, "added")))
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+	m.Mediate(context.Background(), "member", "", []byte(fmt.Sprintf(pullRequestPayloadWithMember, "added")))
 
 	collaboratorsClientMock.AssertExpectations(t)
 }
@@ -299,8 +723,8 @@ func TestMediator_Mediate_EditColaborator(t *testing.T) {
 
 	reviewersService := new(ReviewersServiceMock)
 
-	m := hooks.NewMediatorService(payloadServiceMock, webClientMock, collaboratorsClientMock, reviewersService)
-	m.Mediate("member", []byte(fmt.Sprintf(pullRequestPayloadWithMember, "edited")))
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+	m.Mediate(context.Background(), "member", "", []byte(fmt.Sprintf(pullRequestPayloadWithMember, "edited")))
 
 	collaboratorsClientMock.AssertExpectations(t)
 }
@@ -325,12 +749,331 @@ func TestMediator_Mediate_DeleteColaborator(t *testing.T) {
 
 	reviewersService := new(ReviewersServiceMock)
 
-	m := hooks.NewMediatorService(payloadServiceMock, webClientMock, collaboratorsClientMock, reviewersService)
-	m.Mediate("member", []byte(fmt.Sprintf(pullRequestPayloadWithMember, "deleted")))
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+	m.Mediate(context.Background(), "member", "", []byte(fmt.Sprintf(pullRequestPayloadWithMember, "deleted")))
 
 	collaboratorsClientMock.AssertExpectations(t)
 }
 
+func TestMediator_Mediate_PullRequestReview(t *testing.T) {
+	submittedAt := time.Date(2016, 10, 3, 23, 39, 9, 0, time.UTC)
+
+	review := &bw.Review{
+		Id:                2626884,
+		Action:            "submitted",
+		State:             "approved",
+		Body:              "Looks great!",
+		RepositoryName:    "baxterthehacker/public-repo",
+		PullRequestNumber: 8,
+		ReviewerId:        6752317,
+		ReviewerLogin:     "baxterthehacker",
+		CommitId:          "b7a1f9c27caa4e03c14a88feb56e2d4f7500aa63",
+		SubmittedAt:       &submittedAt,
+	}
+
+	payloadServiceMock := new(PayloadServiceMock)
+
+	collaboratorsClientMock := new(CollaboratorsClientMock)
+
+	webClientMock := new(WebClientMock)
+
+	webClientMock.On("ProcessReview", review).Return(nil)
+
+	reviewersService := new(ReviewersServiceMock)
+
+	m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+	err := m.Mediate(context.Background(), "pull_request_review", "", []byte(pullRequestReviewPayload))
+	require.NoError(t, err)
+
+	webClientMock.AssertExpectations(t)
+}
+
+func TestMediator_Mediate_LogsDelivery(t *testing.T) {
+	webClientMock := new(WebClientMock)
+	webClientMock.On("ProcessReview", mock.Anything).Return(nil)
+
+	m := hooks.NewMediatorService(new(PayloadServiceMock), hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, new(CollaboratorsClientMock), new(ReviewersServiceMock), hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+	m.Use(hooks.LogEvents)
+
+	var buf bytes.Buffer
+	ctx := logging.NewContext(context.Background(), logging.New(&buf, logging.InfoLevel))
+
+	err := m.Mediate(ctx, "pull_request_review", "72d3162e-cc78-11e3-81ab-4c9367dc0958", []byte(pullRequestReviewPayload))
+	require.NoError(t, err)
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+
+	assert.Equal(t, "info", line["level"])
+	assert.Equal(t, "handled pull_request_review event", line["msg"])
+	assert.Equal(t, "72d3162e-cc78-11e3-81ab-4c9367dc0958", line["delivery_id"])
+	assert.Equal(t, "pull_request_review", line["event"])
+	assert.Equal(t, "submitted", line["action"])
+	assert.Equal(t, "baxterthehacker/public-repo", line["repository"])
+	assert.Equal(t, float64(8), line["pull_request"])
+}
+
+func TestMediator_Mediate_PullRequestReviewComment(t *testing.T) {
+	createdAt := time.Date(2015, 5, 5, 23, 40, 27, 0, time.UTC)
+	updatedAt := time.Date(2015, 5, 5, 23, 40, 27, 0, time.UTC)
+
+	for _, action := range []string{"created", "edited", "deleted"} {
+		comment := &bw.ReviewComment{
+			Id:                29724692,
+			Action:            action,
+			Body:              "Maybe you should use more emojji on this line.",
+			RepositoryName:    "baxterthehacker/public-repo",
+			PullRequestNumber: 1,
+			AuthorId:          6752317,
+			AuthorLogin:       "baxterthehacker",
+			Path:              "README.md",
+			Position:          1,
+			CommitId:          "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+			CreatedAt:         &createdAt,
+			UpdatedAt:         &updatedAt,
+		}
+
+		payloadServiceMock := new(PayloadServiceMock)
+
+		collaboratorsClientMock := new(CollaboratorsClientMock)
+
+		webClientMock := new(WebClientMock)
+
+		webClientMock.On("ProcessReviewComment", comment).Return(nil)
+
+		reviewersService := new(ReviewersServiceMock)
+
+		m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+		err := m.Mediate(context.Background(), "pull_request_review_comment", "", []byte(fmt.Sprintf(pullRequestReviewCommentPayload, action)))
+		require.NoError(t, err)
+
+		webClientMock.AssertExpectations(t)
+	}
+}
+
+func TestMediator_Mediate_IssueComment(t *testing.T) {
+	createdAt := time.Date(2015, 5, 5, 23, 40, 28, 0, time.UTC)
+
+	comment := &bw.Comment{
+		Id:                99262140,
+		Action:            "created",
+		Body:              "You are totally right! I'll get this fixed right away.",
+		RepositoryName:    "baxterthehacker/public-repo",
+		PullRequestNumber: 2,
+		AuthorId:          6752317,
+		AuthorLogin:       "baxterthehacker",
+		CreatedAt:         &createdAt,
+		UpdatedAt:         &createdAt,
+	}
+
+	results := []struct {
+		PullRequest string
+		Processed   bool
+	}{
+		{`,"pull_request": {"url": "https://api.github.com/repos/baxterthehacker/public-repo/pulls/2"}`, true},
+		{"", false},
+	}
+
+	for _, result := range results {
+		payloadServiceMock := new(PayloadServiceMock)
+
+		collaboratorsClientMock := new(CollaboratorsClientMock)
+
+		webClientMock := new(WebClientMock)
+
+		webClientMock.On("ProcessComment", comment).Return(nil)
+
+		reviewersService := new(ReviewersServiceMock)
+
+		m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+		err := m.Mediate(context.Background(), "issue_comment", "", []byte(fmt.Sprintf(issueCommentPayload, result.PullRequest)))
+		require.NoError(t, err)
+
+		if result.Processed {
+			webClientMock.AssertExpectations(t)
+		} else {
+			webClientMock.AssertNotCalled(t, "ProcessComment", mock.Anything)
+		}
+	}
+}
+
+func TestMediator_Mediate_Ping(t *testing.T) {
+	results := []struct {
+		HookId      int
+		ContentType string
+		Events      string
+		Err         error
+	}{
+		{HookId: 12345678, ContentType: "json", Events: `"pull_request", "member"`},
+		{HookId: 87654321, ContentType: "json", Events: `"pull_request"`, Err: hooks.ErrInvalidHook},
+		{HookId: 12345678, ContentType: "form", Events: `"pull_request"`, Err: hooks.ErrInvalidHook},
+		{HookId: 12345678, ContentType: "json", Events: `"push"`, Err: hooks.ErrInvalidHook},
+	}
+
+	for _, result := range results {
+		payloadServiceMock := new(PayloadServiceMock)
+
+		trackings := new(TrackingsMock)
+		trackings.On("Confirm", "baxterthehacker/public-repo", 12345678).Return(nil)
+
+		m := hooks.NewMediatorService(payloadServiceMock, hooks.NewMemoryDeliveries(time.Hour), trackings, new(WebClientMock), new(CollaboratorsClientMock), new(ReviewersServiceMock), hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+		err := m.Mediate(context.Background(), "ping", "", []byte(fmt.Sprintf(pingPayload, result.HookId, result.Events, result.ContentType)))
+		assert.Equal(t, result.Err, err)
+
+		if result.Err == nil {
+			trackings.AssertExpectations(t)
+		} else {
+			trackings.AssertNotCalled(t, "Confirm", mock.Anything, mock.Anything)
+			payloadServiceMock.AssertNotCalled(t, "Save", mock.Anything)
+		}
+	}
+}
+
+func TestMediator_Mediate_UnsupportedEvents(t *testing.T) {
+	results := []struct {
+		Event   string
+		Payload string
+		Err     error
+	}{
+		{"commit_comment", `{"action":"created"}`, &hooks.UnsupportedEventError{Event: "commit_comment", Action: "created"}},
+		{"push", `{"ref":"refs/heads/master"}`, &hooks.UnsupportedEventError{Event: "push"}},
+		{"member", fmt.Sprintf(pullRequestPayloadWithMember, "transferred"), &hooks.UnsupportedEventError{Event: "member", Action: "transferred"}},
+	}
+
+	for _, result := range results {
+		payloadServiceMock := new(PayloadServiceMock)
+
+		collaboratorsClientMock := new(CollaboratorsClientMock)
+
+		deliveries := hooks.NewMemoryDeliveries(time.Hour)
+
+		m := hooks.NewMediatorService(payloadServiceMock, deliveries, new(TrackingsMock), new(WebClientMock), collaboratorsClientMock, new(ReviewersServiceMock), hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+		err := m.Mediate(context.Background(), result.Event, "72d3162e-cc78-11e3-81ab-4c9367dc0958", []byte(result.Payload))
+		assert.Equal(t, result.Err, err)
+
+		payloadServiceMock.AssertNotCalled(t, "Save", mock.Anything)
+
+		// delivery is neither processed nor left claimed
+		claimed, err := deliveries.Claim("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+		require.NoError(t, err)
+		assert.True(t, claimed)
+	}
+}
+
+func TestMediator_Mediate_SkipProcessedDeliveries(t *testing.T) {
+
+	collaborator := &gh.Collaborator{
+		Id:    583231,
+		Login: "octocat",
+		Admin: false,
+	}
+
+	payloadServiceMock := new(PayloadServiceMock)
+
+	collaboratorsClientMock := new(CollaboratorsClientMock)
+
+	collaboratorsClientMock.On("AddCollaborator", "baxterthehacker/public-repo", collaborator).Return(nil).Once()
+
+	webClientMock := new(WebClientMock)
+
+	reviewersService := new(ReviewersServiceMock)
+
+	deliveries := hooks.NewMemoryDeliveries(time.Hour)
+
+	m := hooks.NewMediatorService(payloadServiceMock, deliveries, new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+	payload := []byte(fmt.Sprintf(pullRequestPayloadWithMember, "added"))
+
+	require.NoError(t, m.Mediate(context.Background(), "member", "72d3162e-cc78-11e3-81ab-4c9367dc0958", payload))
+	require.NoError(t, m.Mediate(context.Background(), "member", "72d3162e-cc78-11e3-81ab-4c9367dc0958", payload))
+
+	collaboratorsClientMock.AssertExpectations(t)
+	collaboratorsClientMock.AssertNumberOfCalls(t, "AddCollaborator", 1)
+}
+
+func TestMediator_Mediate_RetryFailedDeliveries(t *testing.T) {
+
+	collaborator := &gh.Collaborator{
+		Id:    583231,
+		Login: "octocat",
+		Admin: false,
+	}
+
+	payloadServiceMock := new(PayloadServiceMock)
+
+	payloadServiceMock.On("Save", mock.MatchedBy(func(envelope *hooks.Envelope) bool {
+		return envelope.Event == "member" &&
+			envelope.DeliveryID == "72d3162e-cc78-11e3-81ab-4c9367dc0958" &&
+			envelope.Repository == "baxterthehacker/public-repo" &&
+			envelope.LastError == "unavailable" &&
+			envelope.Attempts == 1
+	})).Return(nil)
+
+	collaboratorsClientMock := new(CollaboratorsClientMock)
+
+	collaboratorsClientMock.On("AddCollaborator", "baxterthehacker/public-repo", collaborator).Return(fmt.Errorf("unavailable")).Once()
+	collaboratorsClientMock.On("AddCollaborator", "baxterthehacker/public-repo", collaborator).Return(nil).Once()
+
+	webClientMock := new(WebClientMock)
+
+	reviewersService := new(ReviewersServiceMock)
+
+	deliveries := hooks.NewMemoryDeliveries(time.Hour)
+
+	m := hooks.NewMediatorService(payloadServiceMock, deliveries, new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+	payload := []byte(fmt.Sprintf(pullRequestPayloadWithMember, "added"))
+
+	err := m.Mediate(context.Background(), "member", "72d3162e-cc78-11e3-81ab-4c9367dc0958", payload)
+	assert.Equal(t, &hooks.SavedForRetryError{Err: fmt.Errorf("unavailable")}, err)
+
+	require.NoError(t, m.Mediate(context.Background(), "member", "72d3162e-cc78-11e3-81ab-4c9367dc0958", payload))
+
+	collaboratorsClientMock.AssertNumberOfCalls(t, "AddCollaborator", 2)
+	payloadServiceMock.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestMediator_Replay(t *testing.T) {
+
+	collaborator := &gh.Collaborator{
+		Id:    583231,
+		Login: "octocat",
+		Admin: false,
+	}
+
+	payloadServiceMock := new(PayloadServiceMock)
+
+	collaboratorsClientMock := new(CollaboratorsClientMock)
+
+	collaboratorsClientMock.On("AddCollaborator", "baxterthehacker/public-repo", collaborator).Return(nil).Once()
+
+	webClientMock := new(WebClientMock)
+
+	reviewersService := new(ReviewersServiceMock)
+
+	deliveries := hooks.NewMemoryDeliveries(time.Hour)
+
+	m := hooks.NewMediatorService(payloadServiceMock, deliveries, new(TrackingsMock), webClientMock, collaboratorsClientMock, reviewersService, hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))), hooks.NewPolicies(hooks.Policy{RequiredReviewers: 1}, nil))
+
+	envelope := &hooks.Envelope{
+		Event:      "member",
+		DeliveryID: "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+		Payload:    fmt.Sprintf(pullRequestPayloadWithMember, "added"),
+	}
+
+	require.NoError(t, m.Replay(context.Background(), envelope))
+
+	// delivery has been processed by replay, so it is skipped when redelivered by GitHub
+	require.NoError(t, m.Mediate(context.Background(), "member", "72d3162e-cc78-11e3-81ab-4c9367dc0958", []byte(envelope.Payload)))
+
+	collaboratorsClientMock.AssertNumberOfCalls(t, "AddCollaborator", 1)
+}
+
 const (
 	pullRequestHookPayloadWithAssignedReviewers = `{
   "action": "opened",
@@ -635,7 +1378,26 @@ const (
     "commits": 1,
     "additions": 1,
     "deletions": 1,
-    "changed_files": 1
+    "changed_files": 1,
+    "requested_reviewers": [{
+      "login": "blamewarrior_second_user",
+      "id": 6752318,
+      "avatar_url": "https://avatars.githubusercontent.com/u/6752318?v=3",
+      "gravatar_id": "",
+      "url": "https://api.github.com/users/blamewarrior_second_user",
+      "html_url": "https://github.com/blamewarrior_second_user",
+      "followers_url": "https://api.github.com/users/blamewarrior_second_user/followers",
+      "following_url": "https://api.github.com/users/blamewarrior_second_user/following{/other_user}",
+      "gists_url": "https://api.github.com/users/blamewarrior_second_user/gists{/gist_id}",
+      "starred_url": "https://api.github.com/users/blamewarrior_second_user/starred{/owner}{/repo}",
+      "subscriptions_url": "https://api.github.com/users/blamewarrior_second_user/subscriptions",
+      "organizations_url": "https://api.github.com/users/blamewarrior_second_user/orgs",
+      "repos_url": "https://api.github.com/users/blamewarrior_second_user/repos",
+      "events_url": "https://api.github.com/users/blamewarrior_second_user/events{/privacy}",
+      "received_events_url": "https://api.github.com/users/blamewarrior_second_user/received_events",
+      "type": "User",
+      "site_admin": false
+    }]
   },
   "repository": {
     "id": 35129377,
@@ -724,25 +1486,6 @@ const (
     "watchers": 0,
     "default_branch": "master"
   },
-  "requested_reviewers": [{
-    "login": "blamewarrior_second_user",
-    "id": 6752318,
-    "avatar_url": "https://avatars.githubusercontent.com/u/6752318?v=3",
-    "gravatar_id": "",
-    "url": "https://api.github.com/users/blamewarrior_second_user",
-    "html_url": "https://github.com/blamewarrior_second_user",
-    "followers_url": "https://api.github.com/users/blamewarrior_second_user/followers",
-    "following_url": "https://api.github.com/users/blamewarrior_second_user/following{/other_user}",
-    "gists_url": "https://api.github.com/users/blamewarrior_second_user/gists{/gist_id}",
-    "starred_url": "https://api.github.com/users/blamewarrior_second_user/starred{/owner}{/repo}",
-    "subscriptions_url": "https://api.github.com/users/blamewarrior_second_user/subscriptions",
-    "organizations_url": "https://api.github.com/users/blamewarrior_second_user/orgs",
-    "repos_url": "https://api.github.com/users/blamewarrior_second_user/repos",
-    "events_url": "https://api.github.com/users/blamewarrior_second_user/events{/privacy}",
-    "received_events_url": "https://api.github.com/users/blamewarrior_second_user/received_events",
-    "type": "User",
-    "site_admin": false
-  }],
   "installation": {
     "id": 234
   }
@@ -1051,7 +1794,8 @@ const (
     "commits": 1,
     "additions": 1,
     "deletions": 1,
-    "changed_files": 1
+    "changed_files": 1,
+    "requested_reviewers": []
   },
   "repository": {
     "id": 35129377,
@@ -1140,7 +1884,6 @@ const (
     "watchers": 0,
     "default_branch": "master"
   },
-  "requested_reviewers": [],
   "installation": {
     "id": 234
   }
@@ -1449,7 +2192,26 @@ const (
     "commits": 1,
     "additions": 1,
     "deletions": 1,
-    "changed_files": 1
+    "changed_files": 1,
+    "requested_reviewers": [{
+      "login": "blamewarrior_second_user",
+      "id": 6752318,
+      "avatar_url": "https://avatars.githubusercontent.com/u/6752318?v=3",
+      "gravatar_id": "",
+      "url": "https://api.github.com/users/blamewarrior_second_user",
+      "html_url": "https://github.com/blamewarrior_second_user",
+      "followers_url": "https://api.github.com/users/blamewarrior_second_user/followers",
+      "following_url": "https://api.github.com/users/blamewarrior_second_user/following{/other_user}",
+      "gists_url": "https://api.github.com/users/blamewarrior_second_user/gists{/gist_id}",
+      "starred_url": "https://api.github.com/users/blamewarrior_second_user/starred{/owner}{/repo}",
+      "subscriptions_url": "https://api.github.com/users/blamewarrior_second_user/subscriptions",
+      "organizations_url": "https://api.github.com/users/blamewarrior_second_user/orgs",
+      "repos_url": "https://api.github.com/users/blamewarrior_second_user/repos",
+      "events_url": "https://api.github.com/users/blamewarrior_second_user/events{/privacy}",
+      "received_events_url": "https://api.github.com/users/blamewarrior_second_user/received_events",
+      "type": "User",
+      "site_admin": false
+    }]
   },
   "repository": {
     "id": 35129377,
@@ -1538,25 +2300,6 @@ const (
     "watchers": 0,
     "default_branch": "master"
   },
-  "requested_reviewers": [{
-    "login": "blamewarrior_second_user",
-    "id": 6752318,
-    "avatar_url": "https://avatars.githubusercontent.com/u/6752318?v=3",
-    "gravatar_id": "",
-    "url": "https://api.github.com/users/blamewarrior_second_user",
-    "html_url": "https://github.com/blamewarrior_second_user",
-    "followers_url": "https://api.github.com/users/blamewarrior_second_user/followers",
-    "following_url": "https://api.github.com/users/blamewarrior_second_user/following{/other_user}",
-    "gists_url": "https://api.github.com/users/blamewarrior_second_user/gists{/gist_id}",
-    "starred_url": "https://api.github.com/users/blamewarrior_second_user/starred{/owner}{/repo}",
-    "subscriptions_url": "https://api.github.com/users/blamewarrior_second_user/subscriptions",
-    "organizations_url": "https://api.github.com/users/blamewarrior_second_user/orgs",
-    "repos_url": "https://api.github.com/users/blamewarrior_second_user/repos",
-    "events_url": "https://api.github.com/users/blamewarrior_second_user/events{/privacy}",
-    "received_events_url": "https://api.github.com/users/blamewarrior_second_user/received_events",
-    "type": "User",
-    "site_admin": false
-  }],
   "installation": {
     "id": 234
   }
@@ -1590,5 +2333,164 @@ const (
     "full_name": "baxterthehacker/public-repo"
   }
 }
+`
+
+	pullRequestReviewPayload = `{
+  "action": "submitted",
+  "review": {
+    "id": 2626884,
+    "user": {
+      "login": "baxterthehacker",
+      "id": 6752317,
+      "type": "User",
+      "site_admin": false
+    },
+    "body": "Looks great!",
+    "commit_id": "b7a1f9c27caa4e03c14a88feb56e2d4f7500aa63",
+    "submitted_at": "2016-10-03T23:39:09Z",
+    "state": "approved",
+    "html_url": "https://github.com/baxterthehacker/public-repo/pull/8#pullrequestreview-2626884",
+    "pull_request_url": "https://api.github.com/repos/baxterthehacker/public-repo/pulls/8"
+  },
+  "pull_request": {
+    "url": "https://api.github.com/repos/baxterthehacker/public-repo/pulls/8",
+    "id": 87811438,
+    "html_url": "https://github.com/baxterthehacker/public-repo/pull/8",
+    "number": 8,
+    "state": "open",
+    "title": "Add a README description",
+    "user": {
+      "login": "skalnik",
+      "id": 2546,
+      "type": "User",
+      "site_admin": true
+    },
+    "body": "Just a few more details"
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "public-repo",
+    "full_name": "baxterthehacker/public-repo"
+  },
+  "sender": {
+    "login": "baxterthehacker",
+    "id": 6752317
+  }
+}
+`
+
+	pullRequestReviewCommentPayload = `{
+  "action": "%s",
+  "comment": {
+    "url": "https://api.github.com/repos/baxterthehacker/public-repo/pulls/comments/29724692",
+    "id": 29724692,
+    "diff_hunk": "@@ -1 +1 @@\n-# public-repo",
+    "path": "README.md",
+    "position": 1,
+    "original_position": 1,
+    "commit_id": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+    "original_commit_id": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+    "user": {
+      "login": "baxterthehacker",
+      "id": 6752317,
+      "type": "User",
+      "site_admin": false
+    },
+    "body": "Maybe you should use more emojji on this line.",
+    "created_at": "2015-05-05T23:40:27Z",
+    "updated_at": "2015-05-05T23:40:27Z",
+    "html_url": "https://github.com/baxterthehacker/public-repo/pull/1#discussion_r29724692",
+    "pull_request_url": "https://api.github.com/repos/baxterthehacker/public-repo/pulls/1"
+  },
+  "pull_request": {
+    "url": "https://api.github.com/repos/baxterthehacker/public-repo/pulls/1",
+    "id": 34778301,
+    "html_url": "https://github.com/baxterthehacker/public-repo/pull/1",
+    "number": 1,
+    "state": "open",
+    "title": "Update the README with new information",
+    "user": {
+      "login": "baxterthehacker",
+      "id": 6752317
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "public-repo",
+    "full_name": "baxterthehacker/public-repo"
+  },
+  "sender": {
+    "login": "baxterthehacker",
+    "id": 6752317
+  }
+}
+`
+
+	issueCommentPayload = `{
+  "action": "created",
+  "issue": {
+    "url": "https://api.github.com/repos/baxterthehacker/public-repo/issues/2",
+    "id": 73464126,
+    "number": 2,
+    "title": "Spelling error in the README file",
+    "user": {
+      "login": "baxterthehacker",
+      "id": 6752317
+    },
+    "state": "open",
+    "body": "It looks like you accidently spelled 'commit' with two 't's."%s
+  },
+  "comment": {
+    "url": "https://api.github.com/repos/baxterthehacker/public-repo/issues/comments/99262140",
+    "html_url": "https://github.com/baxterthehacker/public-repo/issues/2#issuecomment-99262140",
+    "id": 99262140,
+    "user": {
+      "login": "baxterthehacker",
+      "id": 6752317,
+      "type": "User",
+      "site_admin": false
+    },
+    "created_at": "2015-05-05T23:40:28Z",
+    "updated_at": "2015-05-05T23:40:28Z",
+    "body": "You are totally right! I'll get this fixed right away."
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "public-repo",
+    "full_name": "baxterthehacker/public-repo"
+  },
+  "sender": {
+    "login": "baxterthehacker",
+    "id": 6752317
+  }
+}
+`
+
+	pingPayload = `{
+  "zen": "Keep it logically awesome.",
+  "hook_id": 12345678,
+  "hook": {
+    "type": "Repository",
+    "id": %d,
+    "name": "web",
+    "active": true,
+    "events": [%s],
+    "config": {
+      "content_type": "%s",
+      "insecure_ssl": "0",
+      "secret": "********",
+      "url": "https://blamewarrior.com/baxterthehacker/public-repo/webhook"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "public-repo",
+    "full_name": "baxterthehacker/public-repo"
+  },
+  "sender": {
+    "login": "baxterthehacker",
+    "id": 6752317
+  }
+}
 `
 )
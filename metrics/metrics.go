@@ -0,0 +1,355 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package metrics implements counters, gauges and histograms exposed in Prometheus
+// text exposition format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are histogram buckets suitable for request durations in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry keeps registered metrics and writes them out in the order of registration.
+type Registry struct {
+	mu         sync.Mutex
+	families   []*family
+	collectors []func()
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Counter registers a counter partitioned by labels.
+func (registry *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{registry.register(name, help, "counter", labels, func() series {
+		return new(value)
+	})}
+}
+
+// Gauge registers a gauge partitioned by labels.
+func (registry *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{registry.register(name, help, "gauge", labels, func() series {
+		return new(value)
+	})}
+}
+
+// Histogram registers a histogram partitioned by labels. Buckets are upper bounds
+// of observed values, the +Inf bucket is added implicitly.
+func (registry *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+
+	return &HistogramVec{registry.register(name, help, "histogram", labels, func() series {
+		return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+	})}
+}
+
+// OnCollect adds fn to be called before metrics are written, e.g. to update gauges
+// that reflect external state.
+func (registry *Registry) OnCollect(fn func()) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.collectors = append(registry.collectors, fn)
+}
+
+// WriteTo runs collectors and writes all metrics to w.
+func (registry *Registry) WriteTo(w io.Writer) (int64, error) {
+	registry.mu.Lock()
+	families := append([]*family(nil), registry.families...)
+	collectors := append([]func(){}, registry.collectors...)
+	registry.mu.Unlock()
+
+	for _, collect := range collectors {
+		collect()
+	}
+
+	var buf bytes.Buffer
+
+	for _, f := range families {
+		f.write(&buf)
+	}
+
+	return buf.WriteTo(w)
+}
+
+func (registry *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	registry.WriteTo(w)
+}
+
+func (registry *Registry) register(name, help, typ string, labels []string, newSeries func() series) *family {
+	f := &family{
+		name:      name,
+		help:      help,
+		typ:       typ,
+		labels:    labels,
+		newSeries: newSeries,
+		series:    make(map[string]*labeledSeries),
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	for _, registered := range registry.families {
+		if registered.name == name {
+			panic(fmt.Sprintf("metrics: %s is already registered", name))
+		}
+	}
+
+	registry.families = append(registry.families, f)
+
+	return f
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	f *family
+}
+
+// With returns the counter for given label values, they must match registered labels.
+func (vec *CounterVec) With(labelValues ...string) *Counter {
+	return &Counter{vec.f.with(labelValues).(*value)}
+}
+
+type Counter struct {
+	v *value
+}
+
+func (c *Counter) Inc() {
+	c.v.add(1)
+}
+
+// Add increases the counter by delta, negative deltas are ignored.
+func (c *Counter) Add(delta float64) {
+	if delta > 0 {
+		c.v.add(delta)
+	}
+}
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct {
+	f *family
+}
+
+// With returns the gauge for given label values, they must match registered labels.
+func (vec *GaugeVec) With(labelValues ...string) *Gauge {
+	return &Gauge{vec.f.with(labelValues).(*value)}
+}
+
+type Gauge struct {
+	v *value
+}
+
+func (g *Gauge) Set(v float64) {
+	g.v.set(v)
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.v.add(delta)
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	f *family
+}
+
+// With returns the histogram for given label values, they must match registered labels.
+func (vec *HistogramVec) With(labelValues ...string) *Histogram {
+	return &Histogram{vec.f.with(labelValues).(*histogram)}
+}
+
+type Histogram struct {
+	h *histogram
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.h.observe(v)
+}
+
+type family struct {
+	name, help, typ string
+	labels          []string
+	newSeries       func() series
+
+	mu     sync.Mutex
+	series map[string]*labeledSeries
+}
+
+type labeledSeries struct {
+	labelValues []string
+	series
+}
+
+type series interface {
+	write(w io.Writer, name, labels string)
+}
+
+func (f *family) with(labelValues []string) series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &labeledSeries{append([]string(nil), labelValues...), f.newSeries()}
+		f.series[key] = s
+	}
+
+	return s.series
+}
+
+func (f *family) write(w io.Writer) {
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	series := make([]*labeledSeries, 0, len(keys))
+	for _, key := range keys {
+		series = append(series, f.series[key])
+	}
+	f.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, helpEscaper.Replace(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+
+	for _, s := range series {
+		s.write(w, f.name, formatLabels(f.labels, s.labelValues))
+	}
+}
+
+type value struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (v *value) add(delta float64) {
+	v.mu.Lock()
+	v.v += delta
+	v.mu.Unlock()
+}
+
+func (v *value) set(x float64) {
+	v.mu.Lock()
+	v.v = x
+	v.mu.Unlock()
+}
+
+func (v *value) write(w io.Writer, name, labels string) {
+	v.mu.Lock()
+	x := v.v
+	v.mu.Unlock()
+
+	fmt.Fprintf(w, "%s%s %s\n", name, wrapLabels(labels), formatFloat(x))
+}
+
+type histogram struct {
+	bounds []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) write(w io.Writer, name, labels string) {
+	h.mu.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	count, sum := h.count, h.sum
+	h.mu.Unlock()
+
+	if labels != "" {
+		labels += ","
+	}
+
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += counts[i]
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", name, labels, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, count)
+
+	labels = strings.TrimSuffix(labels, ",")
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, wrapLabels(labels), formatFloat(sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, wrapLabels(labels), count)
+}
+
+func formatLabels(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=\"%s\"", name, labelEscaper.Replace(values[i]))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+
+	return "{" + labels + "}"
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
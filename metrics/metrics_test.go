@@ -0,0 +1,141 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package metrics_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blamewarrior/hooks/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Counter(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	deliveries := registry.Counter("deliveries_total", "Deliveries received.", "event", "result")
+	deliveries.With("pull_request", "processed").Inc()
+	deliveries.With("pull_request", "processed").Add(2)
+	deliveries.With("member", "failed").Inc()
+	deliveries.With("member", "failed").Add(-1)
+
+	var buf bytes.Buffer
+	_, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, `# HELP deliveries_total Deliveries received.
+# TYPE deliveries_total counter
+deliveries_total{event="member",result="failed"} 1
+deliveries_total{event="pull_request",result="processed"} 3
+`, buf.String())
+}
+
+func TestRegistry_Gauge(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	length := registry.Gauge("queue_length", "Entries in queue.", "list")
+	registry.OnCollect(func() {
+		length.With("hooks").Set(5)
+	})
+
+	length.With(`dead "hooks"`).Set(1)
+	length.With(`dead "hooks"`).Add(-0.5)
+
+	var buf bytes.Buffer
+	_, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, `# HELP queue_length Entries in queue.
+# TYPE queue_length gauge
+queue_length{list="dead \"hooks\""} 0.5
+queue_length{list="hooks"} 5
+`, buf.String())
+}
+
+func TestRegistry_Histogram(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	duration := registry.Histogram("request_duration_seconds", "Request duration.", []float64{1, 0.1})
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		duration.With().Observe(v)
+	}
+
+	var buf bytes.Buffer
+	_, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, `# HELP request_duration_seconds Request duration.
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 2
+request_duration_seconds_bucket{le="1"} 3
+request_duration_seconds_bucket{le="+Inf"} 4
+request_duration_seconds_sum 3.65
+request_duration_seconds_count 4
+`, buf.String())
+}
+
+func TestRegistry_HistogramWithLabels(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	duration := registry.Histogram("outbound_duration_seconds", "Outbound request duration.", []float64{0.5}, "client")
+	duration.With("web").Observe(0.25)
+
+	var buf bytes.Buffer
+	_, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, `# HELP outbound_duration_seconds Outbound request duration.
+# TYPE outbound_duration_seconds histogram
+outbound_duration_seconds_bucket{client="web",le="0.5"} 1
+outbound_duration_seconds_bucket{client="web",le="+Inf"} 1
+outbound_duration_seconds_sum{client="web"} 0.25
+outbound_duration_seconds_count{client="web"} 1
+`, buf.String())
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.Counter("pings_total", "Pings.").With().Inc()
+
+	w := httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "# HELP pings_total Pings.\n# TYPE pings_total counter\npings_total 1\n", w.Body.String())
+}
+
+func TestRegistry_LabelsMismatch(t *testing.T) {
+	registry := metrics.NewRegistry()
+	counter := registry.Counter("deliveries_total", "Deliveries received.", "event")
+
+	assert.Panics(t, func() {
+		counter.With("pull_request", "opened")
+	})
+}
+
+func TestRegistry_DuplicateName(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.Counter("deliveries_total", "Deliveries received.")
+
+	assert.Panics(t, func() {
+		registry.Gauge("deliveries_total", "Deliveries received.")
+	})
+}
@@ -16,13 +16,33 @@
 package hooks
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/go-redis/redis"
 )
 
+// Envelope is a payload of failed delivery stored along with the data required to replay it.
+type Envelope struct {
+	Event         string    `json:"event"`
+	DeliveryID    string    `json:"delivery_id"`
+	Repository    string    `json:"repository"`
+	Payload       string    `json:"payload"`
+	ReceivedAt    time.Time `json:"received_at"`
+	LastError     string    `json:"last_error"`
+	Attempts      int       `json:"attempts"`
+	LastAttemptAt time.Time `json:"last_attempt_at"`
+
+	// raw keeps the stored representation to be able to remove the envelope from the list
+	raw string
+}
+
 type Payloads interface {
-	Save(payload string) error
-	List(limit int64) ([]string, error)
-	Delete(payload string) error
+	Save(envelope *Envelope) error
+	List(limit int64) ([]*Envelope, error)
+	Rotate(limit int64) ([]*Envelope, error)
+	Delete(envelope *Envelope) error
+	Bury(envelope *Envelope) error
 }
 
 type PayloadRepository struct {
@@ -33,14 +53,131 @@ func NewPayloadRepository(redisClient *redis.Client) *PayloadRepository {
 	return &PayloadRepository{redisClient}
 }
 
-func (repo *PayloadRepository) Save(payload string) (err error) {
-	return repo.redisClient.LPush("hooks", payload).Err()
+func (repo *PayloadRepository) Save(envelope *Envelope) (err error) {
+	raw, err := envelope.marshal()
+	if err != nil {
+		return err
+	}
+
+	return repo.redisClient.LPush("hooks", raw).Err()
+}
+
+// List returns up to limit most recently saved envelopes.
+func (repo *PayloadRepository) List(limit int64) ([]*Envelope, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	values, err := repo.redisClient.LRange("hooks", 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	envelopes := make([]*Envelope, 0, len(values))
+
+	for _, value := range values {
+		envelopes = append(envelopes, unmarshalEnvelope(value))
+	}
+
+	return envelopes, nil
 }
 
-func (repo *PayloadRepository) List(limit int64) ([]string, error) {
-	return repo.redisClient.LRange("hooks", 0, limit).Result()
+// Rotate returns up to limit envelopes saved the earliest and moves them to the head
+// of the list, so that subsequent calls go through the rest of saved envelopes.
+func (repo *PayloadRepository) Rotate(limit int64) ([]*Envelope, error) {
+	n, err := repo.redisClient.LLen("hooks").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if n > limit {
+		n = limit
+	}
+
+	envelopes := make([]*Envelope, 0, n)
+
+	for i := int64(0); i < n; i++ {
+		value, err := repo.redisClient.RPopLPush("hooks", "hooks").Result()
+		if err == redis.Nil {
+			// the list has been drained concurrently
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		envelopes = append(envelopes, unmarshalEnvelope(value))
+	}
+
+	return envelopes, nil
 }
 
-func (repo *PayloadRepository) Delete(payload string) error {
-	return repo.redisClient.LRem("hooks", 0, payload).Err()
+func (repo *PayloadRepository) Delete(envelope *Envelope) error {
+	raw, err := envelope.marshal()
+	if err != nil {
+		return err
+	}
+
+	return repo.redisClient.LRem("hooks", 0, raw).Err()
+}
+
+// Bury saves the envelope to the dead-letter list where it is not replayed from.
+func (repo *PayloadRepository) Bury(envelope *Envelope) error {
+	raw, err := envelope.marshal()
+	if err != nil {
+		return err
+	}
+
+	return repo.redisClient.LPush("dead_hooks", raw).Err()
+}
+
+// unmarshalEnvelope decodes stored envelope. Entries saved before envelopes were
+// introduced contain bare payload, the event for them is guessed from the payload.
+func unmarshalEnvelope(value string) *Envelope {
+	envelope := &Envelope{raw: value}
+
+	if err := json.Unmarshal([]byte(value), envelope); err == nil && envelope.Event != "" {
+		return envelope
+	}
+
+	legacy := &Envelope{Payload: value, raw: value}
+
+	var payload struct {
+		PullRequest json.RawMessage `json:"pull_request"`
+		Member      json.RawMessage `json:"member"`
+		Repository  struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+
+	if err := json.Unmarshal([]byte(value), &payload); err != nil {
+		return legacy
+	}
+
+	switch {
+	case payload.PullRequest != nil:
+		legacy.Event = "pull_request"
+	case payload.Member != nil:
+		legacy.Event = "member"
+	}
+
+	legacy.Repository = payload.Repository.FullName
+
+	return legacy
+}
+
+func (envelope *Envelope) marshal() (string, error) {
+	if envelope.raw != "" {
+		return envelope.raw, nil
+	}
+
+	b, err := json.Marshal(envelope)
+	if err != nil {
+		return "", err
+	}
+
+	envelope.raw = string(b)
+
+	return envelope.raw, nil
 }
@@ -20,6 +20,7 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/blamewarrior/hooks"
 	"github.com/go-redis/redis"
@@ -35,16 +36,25 @@ func TestSavePayload(t *testing.T) {
 
 	payloadRepo := hooks.NewPayloadRepository(redisClient)
 
-	testPayload := "test_payload"
+	envelope := &hooks.Envelope{
+		Event:         "member",
+		DeliveryID:    "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+		Repository:    "baxterthehacker/public-repo",
+		Payload:       `{"action":"added"}`,
+		ReceivedAt:    time.Date(2017, 12, 30, 10, 0, 0, 0, time.UTC),
+		LastError:     "unavailable",
+		Attempts:      1,
+		LastAttemptAt: time.Date(2017, 12, 30, 10, 0, 0, 0, time.UTC),
+	}
 
-	err := payloadRepo.Save(testPayload)
+	err := payloadRepo.Save(envelope)
 
 	require.NoError(t, err)
 
 	val, err := redisClient.LRange("hooks", 0, 0).Result()
 	require.NoError(t, err)
 	require.Equal(t, 1, len(val))
-	assert.Equal(t, testPayload, val[0])
+	assert.JSONEq(t, testEnvelope, val[0])
 }
 
 func TestListPayload(t *testing.T) {
@@ -52,9 +62,82 @@ func TestListPayload(t *testing.T) {
 
 	defer teardown()
 
-	testPayload := "test_payload"
+	err := createTestPayload(redisClient, testEnvelope)
+	require.NoError(t, err)
 
-	err := createTestPayload(redisClient, testPayload)
+	payloadRepo := hooks.NewPayloadRepository(redisClient)
+
+	list, err := payloadRepo.List(int64(2))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+
+	assert.Equal(t, "member", list[0].Event)
+	assert.Equal(t, "72d3162e-cc78-11e3-81ab-4c9367dc0958", list[0].DeliveryID)
+	assert.Equal(t, "baxterthehacker/public-repo", list[0].Repository)
+	assert.Equal(t, `{"action":"added"}`, list[0].Payload)
+	assert.Equal(t, time.Date(2017, 12, 30, 10, 0, 0, 0, time.UTC), list[0].ReceivedAt)
+	assert.Equal(t, "unavailable", list[0].LastError)
+	assert.Equal(t, 1, list[0].Attempts)
+	assert.Equal(t, time.Date(2017, 12, 30, 10, 0, 0, 0, time.UTC), list[0].LastAttemptAt)
+}
+
+func TestListPayload_Limit(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, createTestPayload(redisClient, testEnvelope))
+	}
+
+	payloadRepo := hooks.NewPayloadRepository(redisClient)
+
+	list, err := payloadRepo.List(int64(2))
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
+func TestRotatePayload(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	payloadRepo := hooks.NewPayloadRepository(redisClient)
+
+	for _, deliveryID := range []string{"first", "second", "third"} {
+		require.NoError(t, payloadRepo.Save(&hooks.Envelope{Event: "member", DeliveryID: deliveryID}))
+	}
+
+	deliveryIDs := func(envelopes []*hooks.Envelope) []string {
+		var ids []string
+		for _, envelope := range envelopes {
+			ids = append(ids, envelope.DeliveryID)
+		}
+		return ids
+	}
+
+	list, err := payloadRepo.Rotate(int64(2))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, deliveryIDs(list))
+
+	list, err = payloadRepo.Rotate(int64(2))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"third", "first"}, deliveryIDs(list))
+
+	// the list is not visited twice within one call
+	list, err = payloadRepo.Rotate(int64(10))
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+}
+
+func TestListLegacyPayload(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	legacyPayload := `{"action":"added","member":{"login":"octocat"},"repository":{"full_name":"baxterthehacker/public-repo"}}`
+
+	err := createTestPayload(redisClient, legacyPayload)
 	require.NoError(t, err)
 
 	payloadRepo := hooks.NewPayloadRepository(redisClient)
@@ -62,7 +145,18 @@ func TestListPayload(t *testing.T) {
 	list, err := payloadRepo.List(int64(2))
 	require.NoError(t, err)
 	require.Equal(t, 1, len(list))
-	require.Equal(t, testPayload, list[0])
+
+	assert.Equal(t, "member", list[0].Event)
+	assert.Equal(t, "baxterthehacker/public-repo", list[0].Repository)
+	assert.Equal(t, legacyPayload, list[0].Payload)
+	assert.Equal(t, 0, list[0].Attempts)
+
+	err = payloadRepo.Delete(list[0])
+	require.NoError(t, err)
+
+	val, err := redisClient.LRange("hooks", 0, 0).Result()
+	require.NoError(t, err)
+	assert.Empty(t, val)
 }
 
 func TestDeletePayload(t *testing.T) {
@@ -70,14 +164,16 @@ func TestDeletePayload(t *testing.T) {
 
 	defer teardown()
 
-	testPayload := "test_payload"
-
-	err := createTestPayload(redisClient, testPayload)
+	err := createTestPayload(redisClient, testEnvelope)
 	require.NoError(t, err)
 
 	payloadRepo := hooks.NewPayloadRepository(redisClient)
 
-	err = payloadRepo.Delete(testPayload)
+	list, err := payloadRepo.List(int64(2))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+
+	err = payloadRepo.Delete(list[0])
 	require.NoError(t, err)
 
 	val, err := redisClient.LRange("hooks", 0, 0).Result()
@@ -86,6 +182,29 @@ func TestDeletePayload(t *testing.T) {
 	assert.Empty(t, val)
 }
 
+func TestBuryPayload(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	err := createTestPayload(redisClient, testEnvelope)
+	require.NoError(t, err)
+
+	payloadRepo := hooks.NewPayloadRepository(redisClient)
+
+	list, err := payloadRepo.List(int64(2))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+
+	err = payloadRepo.Bury(list[0])
+	require.NoError(t, err)
+
+	val, err := redisClient.LRange("dead_hooks", 0, 0).Result()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(val))
+	assert.Equal(t, testEnvelope, val[0])
+}
+
 func createTestPayload(client *redis.Client, testPayload string) error {
 	return client.LPush("hooks", testPayload).Err()
 }
@@ -120,3 +239,5 @@ func setup() (client *redis.Client, teardownFn func()) {
 	}
 
 }
+
+const testEnvelope = `{"event":"member","delivery_id":"72d3162e-cc78-11e3-81ab-4c9367dc0958","repository":"baxterthehacker/public-repo","payload":"{\"action\":\"added\"}","received_at":"2017-12-30T10:00:00Z","last_error":"unavailable","attempts":1,"last_attempt_at":"2017-12-30T10:00:00Z"}`
@@ -0,0 +1,42 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+// Policy configures how reviewers are assigned to pull requests of repository.
+type Policy struct {
+	RequiredReviewers int
+	// Team is the slug of a team requested to review every pull request, if set.
+	Team string
+}
+
+// Policies holds policies configured for particular repositories and the default
+// policy for the rest of them.
+type Policies struct {
+	defaultPolicy Policy
+	policies      map[string]Policy
+}
+
+func NewPolicies(defaultPolicy Policy, policies map[string]Policy) *Policies {
+	return &Policies{defaultPolicy, policies}
+}
+
+func (policies *Policies) For(repoFullName string) Policy {
+	if policy, ok := policies.policies[repoFullName]; ok {
+		return policy
+	}
+
+	return policies.defaultPolicy
+}
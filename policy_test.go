@@ -0,0 +1,36 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"testing"
+
+	"github.com/blamewarrior/hooks"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicies_For(t *testing.T) {
+	policies := hooks.NewPolicies(
+		hooks.Policy{RequiredReviewers: 1},
+		map[string]hooks.Policy{
+			"blamewarrior/hooks": {RequiredReviewers: 2},
+		},
+	)
+
+	assert.Equal(t, hooks.Policy{RequiredReviewers: 2}, policies.For("blamewarrior/hooks"))
+	assert.Equal(t, hooks.Policy{RequiredReviewers: 1}, policies.For("blamewarrior/users"))
+}
@@ -0,0 +1,108 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+import (
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Queue holds deliveries waiting to be processed asynchronously. Popped deliveries
+// stay in the queue until they are acknowledged, so that the ones in flight when
+// the service stopped are not lost.
+type Queue interface {
+	Push(envelope *Envelope) error
+	// Pop waits up to timeout for the next delivery, returns nil if there is none
+	Pop(timeout time.Duration) (*Envelope, error)
+	Ack(envelope *Envelope) error
+	// Requeue returns unacknowledged deliveries back to the queue
+	Requeue() (int, error)
+}
+
+type QueueRepository struct {
+	redisClient *redis.Client
+}
+
+func NewQueueRepository(redisClient *redis.Client) *QueueRepository {
+	return &QueueRepository{redisClient}
+}
+
+func (repo *QueueRepository) Push(envelope *Envelope) error {
+	raw, err := envelope.marshal()
+	if err != nil {
+		return err
+	}
+
+	return repo.redisClient.LPush("queued_hooks", raw).Err()
+}
+
+// Pop waits for the next delivery, redis supports whole seconds only so shorter
+// timeouts are rounded up to a second.
+func (repo *QueueRepository) Pop(timeout time.Duration) (*Envelope, error) {
+	if timeout < time.Second {
+		timeout = time.Second
+	}
+
+	value, err := repo.redisClient.BRPopLPush("queued_hooks", "processing_hooks", timeout).Result()
+
+	if err == redis.Nil {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalEnvelope(value), nil
+}
+
+func (repo *QueueRepository) Ack(envelope *Envelope) error {
+	raw, err := envelope.marshal()
+	if err != nil {
+		return err
+	}
+
+	return repo.redisClient.LRem("processing_hooks", 1, raw).Err()
+}
+
+// Requeue moves unacknowledged deliveries to the front of the queue keeping their
+// order. A delivery is pushed before it is removed from the processing list, so
+// it may be queued twice but is never lost, duplicates are skipped by Mediator.
+func (repo *QueueRepository) Requeue() (int, error) {
+	n := 0
+
+	for {
+		values, err := repo.redisClient.LRange("processing_hooks", 0, 0).Result()
+		if err != nil {
+			return n, err
+		}
+
+		if len(values) == 0 {
+			return n, nil
+		}
+
+		if err = repo.redisClient.RPush("queued_hooks", values[0]).Err(); err != nil {
+			return n, err
+		}
+
+		if err = repo.redisClient.LRem("processing_hooks", 1, values[0]).Err(); err != nil {
+			return n, err
+		}
+
+		n++
+	}
+}
@@ -0,0 +1,101 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blamewarrior/hooks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueRepository_PushPopAck(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	queue := hooks.NewQueueRepository(redisClient)
+
+	for _, deliveryID := range []string{"1", "2"} {
+		require.NoError(t, queue.Push(&hooks.Envelope{Event: "member", DeliveryID: deliveryID}))
+	}
+
+	envelope, err := queue.Pop(time.Second)
+	require.NoError(t, err)
+	require.NotNil(t, envelope)
+	assert.Equal(t, "1", envelope.DeliveryID)
+
+	processing, err := redisClient.LLen("processing_hooks").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), processing)
+
+	require.NoError(t, queue.Ack(envelope))
+
+	processing, err = redisClient.LLen("processing_hooks").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), processing)
+
+	envelope, err = queue.Pop(time.Second)
+	require.NoError(t, err)
+	require.NotNil(t, envelope)
+	assert.Equal(t, "2", envelope.DeliveryID)
+}
+
+func TestQueueRepository_PopEmpty(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	envelope, err := hooks.NewQueueRepository(redisClient).Pop(time.Second)
+	require.NoError(t, err)
+	assert.Nil(t, envelope)
+}
+
+func TestQueueRepository_Requeue(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	queue := hooks.NewQueueRepository(redisClient)
+
+	for _, deliveryID := range []string{"1", "2", "3"} {
+		require.NoError(t, queue.Push(&hooks.Envelope{Event: "member", DeliveryID: deliveryID}))
+	}
+
+	// deliveries 1 and 2 were in flight when the service stopped
+	for i := 0; i < 2; i++ {
+		_, err := queue.Pop(time.Second)
+		require.NoError(t, err)
+	}
+
+	n, err := queue.Requeue()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	var popped []string
+	for i := 0; i < 3; i++ {
+		envelope, err := queue.Pop(time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, envelope)
+
+		popped = append(popped, envelope.DeliveryID)
+	}
+
+	assert.Equal(t, []string{"1", "2", "3"}, popped)
+}
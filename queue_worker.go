@@ -0,0 +1,347 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blamewarrior/hooks/logging"
+)
+
+// QueueMediator mediates queued deliveries. Replay is used to retry failed ones
+// in place without saving them for later retry.
+type QueueMediator interface {
+	Mediator
+	Replayer
+}
+
+// QueueWorker processes queued deliveries with a pool of Workers. Deliveries of
+// the same pull request are processed one after another in the order they were
+// received, see orderingKey, while deliveries of other pull requests are processed
+// in parallel. Failed deliveries are retried in place up to MaxAttempts times, so
+// that the ones received later do not overtake them, and then saved for retry.
+type QueueWorker struct {
+	Workers     int
+	PollTimeout time.Duration
+	// MaxPending limits the number of popped deliveries waiting for deliveries
+	// received before them to be processed
+	MaxPending   int
+	MaxAttempts  int
+	RetryBackoff time.Duration
+
+	queue    Queue
+	mediator QueueMediator
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func NewQueueWorker(queue Queue, mediator QueueMediator, workers int) *QueueWorker {
+	return &QueueWorker{
+		Workers:      workers,
+		PollTimeout:  time.Second,
+		MaxPending:   1000,
+		MaxAttempts:  3,
+		RetryBackoff: time.Second,
+
+		queue:    queue,
+		mediator: mediator,
+
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Run processes queued deliveries until ctx is done or Shutdown is called. Deliveries
+// left unacknowledged by previous run are processed first. Deliveries in flight are
+// mediated with ctx, cancelling it aborts them.
+func (worker *QueueWorker) Run(ctx context.Context) error {
+	defer close(worker.done)
+
+	n, err := worker.queue.Requeue()
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		logging.FromContext(ctx).Infof("requeued %d unacknowledged deliveries", n)
+	}
+
+	workers := worker.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	pending := newKeyedQueue(worker.MaxPending)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				key, envelope, ok := pending.next()
+				if !ok {
+					return
+				}
+
+				// unacknowledged delivery is requeued on the next run
+				if ctx.Err() == nil {
+					worker.process(ctx, envelope)
+				}
+
+				pending.done(key)
+			}
+		}()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-worker.stop:
+		case <-stopped:
+		}
+		pending.close()
+	}()
+
+	defer func() {
+		close(stopped)
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-worker.stop:
+			return nil
+		default:
+		}
+
+		envelope, err := worker.queue.Pop(worker.PollTimeout)
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Errorf("failed to pop queued delivery")
+
+			select {
+			case <-time.After(worker.PollTimeout):
+			case <-ctx.Done():
+			case <-worker.stop:
+			}
+			continue
+		}
+
+		if envelope == nil {
+			continue
+		}
+
+		if !pending.push(orderingKey(envelope), envelope) {
+			return nil
+		}
+	}
+}
+
+// Shutdown stops popping deliveries from the queue and waits for the ones in flight
+// to be processed until ctx is done.
+func (worker *QueueWorker) Shutdown(ctx context.Context) error {
+	worker.stopOnce.Do(func() {
+		close(worker.stop)
+	})
+
+	select {
+	case <-worker.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// process mediates the delivery and acknowledges it. Mediator saves the payload
+// of failed delivery for later retry, so the delivery is acknowledged either way.
+func (worker *QueueWorker) process(ctx context.Context, envelope *Envelope) {
+	logger := logging.FromContext(ctx).WithFields(logging.Fields{
+		"event":       envelope.Event,
+		"delivery_id": envelope.DeliveryID,
+		"repository":  envelope.Repository,
+	})
+
+	err := worker.mediate(logging.NewContext(ctx, logger), envelope)
+	if err != nil && err == ctx.Err() {
+		// unacknowledged delivery is requeued on the next run
+		return
+	}
+
+	switch err.(type) {
+	case nil:
+	case *SavedForRetryError:
+		logger.WithError(err).Warnf("saved queued delivery for retry")
+	case *UnsupportedEventError:
+		logger.WithError(err).Warnf("skipped unsupported queued event")
+	default:
+		logger.WithError(err).Errorf("failed to process queued delivery")
+	}
+
+	if err = worker.queue.Ack(envelope); err != nil {
+		logger.WithError(err).Errorf("failed to acknowledge queued delivery")
+	}
+}
+
+// mediate retries failed delivery in place with backoff, the last attempt saves
+// it for retry if it fails too
+func (worker *QueueWorker) mediate(ctx context.Context, envelope *Envelope) error {
+	backoff := worker.RetryBackoff
+
+	for attempt := 1; attempt < worker.MaxAttempts; attempt++ {
+		err := worker.mediator.Replay(ctx, envelope)
+		if err == nil || isPermanent(err) {
+			return err
+		}
+
+		logging.FromContext(ctx).WithError(err).With("attempts", attempt).Warnf("failed to process queued delivery, retrying")
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return worker.mediator.Mediate(ctx, envelope.Event, envelope.DeliveryID, []byte(envelope.Payload))
+}
+
+// orderingKey returns the key of the sequence delivery belongs to. Events of a pull
+// request or an issue are ordered within it, so that e.g. opened, synchronize and
+// closed reach the web service in this order, while other repository events are
+// ordered within the repository.
+func orderingKey(envelope *Envelope) string {
+	if number := pullRequestNumber([]byte(envelope.Payload)); number > 0 {
+		return fmt.Sprintf("%s#%d", envelope.Repository, number)
+	}
+
+	return envelope.Repository
+}
+
+// keyedQueue hands out deliveries so that the ones with the same key are processed
+// one at a time in the order they were pushed, while deliveries with other keys
+// are not held up by them.
+type keyedQueue struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	pending map[string][]*Envelope
+	// ready keys have pending deliveries and none in flight
+	ready  []string
+	busy   map[string]bool
+	size   int
+	limit  int
+	closed bool
+}
+
+func newKeyedQueue(limit int) *keyedQueue {
+	q := &keyedQueue{
+		pending: make(map[string][]*Envelope),
+		busy:    make(map[string]bool),
+		limit:   limit,
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	return q
+}
+
+// push adds delivery waiting while there are too many pending ones, it reports
+// false if the queue has been closed
+func (q *keyedQueue) push(key string, envelope *Envelope) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.limit > 0 && q.size >= q.limit && !q.closed {
+		q.cond.Wait()
+	}
+
+	if q.closed {
+		return false
+	}
+
+	q.pending[key] = append(q.pending[key], envelope)
+	q.size++
+
+	if !q.busy[key] && len(q.pending[key]) == 1 {
+		q.ready = append(q.ready, key)
+	}
+
+	q.cond.Broadcast()
+
+	return true
+}
+
+// next returns the earliest pending delivery of a key that has none in flight,
+// it waits for one and reports false once the queue is closed and drained
+func (q *keyedQueue) next() (string, *Envelope, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.ready) == 0 {
+		if q.closed && q.size == 0 {
+			return "", nil, false
+		}
+		q.cond.Wait()
+	}
+
+	key := q.ready[0]
+	q.ready = q.ready[1:]
+
+	envelope := q.pending[key][0]
+	if q.pending[key] = q.pending[key][1:]; len(q.pending[key]) == 0 {
+		delete(q.pending, key)
+	}
+
+	q.busy[key] = true
+	q.size--
+
+	q.cond.Broadcast()
+
+	return key, envelope, true
+}
+
+// done marks the delivery of key returned by next as processed
+func (q *keyedQueue) done(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.busy, key)
+
+	if len(q.pending[key]) > 0 {
+		q.ready = append(q.ready, key)
+	}
+
+	q.cond.Broadcast()
+}
+
+// close stops accepting deliveries, pending ones are still handed out
+func (q *keyedQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
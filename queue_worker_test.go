@@ -0,0 +1,404 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blamewarrior/hooks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMediator records the order deliveries are mediated in per ordering key
+// along with keys that had more than one delivery in flight at a time.
+type recordingMediator struct {
+	keys map[string]string // delivery ID -> ordering key
+
+	mu          sync.Mutex
+	deliveries  map[string][]string
+	inFlight    map[string]int
+	overlapping []string
+	concurrent  int
+	maxInFlight int
+	pending     int
+	done        chan struct{}
+}
+
+func newRecordingMediator(keys map[string]string) *recordingMediator {
+	return &recordingMediator{
+		keys:       keys,
+		deliveries: make(map[string][]string),
+		inFlight:   make(map[string]int),
+		pending:    len(keys),
+		done:       make(chan struct{}),
+	}
+}
+
+func (m *recordingMediator) Supports(event, action string) bool {
+	return true
+}
+
+func (m *recordingMediator) Replay(ctx context.Context, envelope *hooks.Envelope) error {
+	return m.Mediate(ctx, envelope.Event, envelope.DeliveryID, []byte(envelope.Payload))
+}
+
+func (m *recordingMediator) Mediate(ctx context.Context, event, deliveryID string, payload []byte) error {
+	key := m.keys[deliveryID]
+
+	m.mu.Lock()
+	m.inFlight[key]++
+	if m.inFlight[key] > 1 {
+		m.overlapping = append(m.overlapping, key)
+	}
+	m.deliveries[key] = append(m.deliveries[key], deliveryID)
+	if m.concurrent++; m.concurrent > m.maxInFlight {
+		m.maxInFlight = m.concurrent
+	}
+	m.mu.Unlock()
+
+	// keep the delivery in flight long enough for others to overlap with it
+	time.Sleep(5 * time.Millisecond)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight[key]--
+	m.concurrent--
+
+	if m.pending--; m.pending == 0 {
+		close(m.done)
+	}
+
+	return nil
+}
+
+type queuedEvent struct {
+	Event, Action, Repository string
+	Number                    int
+}
+
+func (e queuedEvent) payload() string {
+	if e.Event == "issue_comment" {
+		return fmt.Sprintf(`{"action":%q,"issue":{"number":%d},"repository":{"full_name":%q}}`, e.Action, e.Number, e.Repository)
+	}
+
+	return fmt.Sprintf(`{"action":%q,"number":%d,"pull_request":{"number":%d},"repository":{"full_name":%q}}`, e.Action, e.Number, e.Number, e.Repository)
+}
+
+func TestQueueWorker_Run_OrderedWithinPullRequest(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	queue := hooks.NewQueueRepository(redisClient)
+
+	repos := []string{"octocat/hello-world", "octocat/spoon-knife", "blamewarrior/hooks"}
+	lifecycle := []queuedEvent{
+		{Event: "pull_request", Action: "opened"},
+		{Event: "pull_request_review", Action: "submitted"},
+		{Event: "pull_request", Action: "synchronize"},
+		{Event: "issue_comment", Action: "created"},
+		{Event: "pull_request", Action: "synchronize"},
+		{Event: "pull_request", Action: "closed"},
+	}
+
+	keys := make(map[string]string)
+	expected := make(map[string][]string)
+
+	// deliveries of pull requests interleave with each other
+	for step, e := range lifecycle {
+		for _, repo := range repos {
+			for number := 1; number <= 3; number++ {
+				e.Repository, e.Number = repo, number
+
+				key := fmt.Sprintf("%s#%d", repo, number)
+				deliveryID := fmt.Sprintf("%s-%d", key, step)
+
+				require.NoError(t, queue.Push(&hooks.Envelope{
+					Event:      e.Event,
+					DeliveryID: deliveryID,
+					Repository: repo,
+					Payload:    e.payload(),
+				}))
+
+				keys[deliveryID] = key
+				expected[key] = append(expected[key], deliveryID)
+			}
+		}
+	}
+
+	mediator := newRecordingMediator(keys)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- hooks.NewQueueWorker(queue, mediator, 4).Run(ctx)
+	}()
+
+	select {
+	case <-mediator.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued deliveries were not processed in time")
+	}
+
+	cancel()
+	require.NoError(t, <-stopped)
+
+	assert.Equal(t, expected, mediator.deliveries)
+	assert.Empty(t, mediator.overlapping, "deliveries of the same pull request were processed concurrently")
+	assert.True(t, mediator.maxInFlight > 1, "deliveries of different pull requests were not processed in parallel")
+
+	processing, err := redisClient.LLen("processing_hooks").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), processing)
+}
+
+func TestQueueWorker_Run_OrderedWithinRepository(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	queue := hooks.NewQueueRepository(redisClient)
+
+	keys := make(map[string]string)
+	expected := make(map[string][]string)
+
+	for i, action := range []string{"added", "edited", "deleted", "added"} {
+		for _, repo := range []string{"octocat/hello-world", "octocat/spoon-knife"} {
+			deliveryID := fmt.Sprintf("%s-%d", repo, i)
+
+			require.NoError(t, queue.Push(&hooks.Envelope{
+				Event:      "member",
+				DeliveryID: deliveryID,
+				Repository: repo,
+				Payload:    fmt.Sprintf(`{"action":%q,"member":{"login":"octocat"},"repository":{"full_name":%q}}`, action, repo),
+			}))
+
+			keys[deliveryID] = repo
+			expected[repo] = append(expected[repo], deliveryID)
+		}
+	}
+
+	mediator := newRecordingMediator(keys)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- hooks.NewQueueWorker(queue, mediator, 4).Run(ctx)
+	}()
+
+	select {
+	case <-mediator.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued deliveries were not processed in time")
+	}
+
+	cancel()
+	require.NoError(t, <-stopped)
+
+	assert.Equal(t, expected, mediator.deliveries)
+	assert.Empty(t, mediator.overlapping, "deliveries of the same repository were processed concurrently")
+}
+
+func TestQueueWorker_Shutdown(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	queue := hooks.NewQueueRepository(redisClient)
+
+	require.NoError(t, queue.Push(&hooks.Envelope{
+		Event:      "member",
+		DeliveryID: "1",
+		Repository: "octocat/hello-world",
+		Payload:    `{"action":"added","repository":{"full_name":"octocat/hello-world"}}`,
+	}))
+
+	mediator := newRecordingMediator(map[string]string{"1": "octocat/hello-world"})
+	worker := hooks.NewQueueWorker(queue, mediator, 1)
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- worker.Run(context.Background())
+	}()
+
+	<-mediator.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, worker.Shutdown(ctx))
+	require.NoError(t, <-stopped)
+
+	processing, err := redisClient.LLen("processing_hooks").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), processing)
+}
+
+// blockingMediator holds deliveries of the blocked repository until released,
+// deliveries of the failing one fail until their attempts run out
+type blockingMediator struct {
+	mu        sync.Mutex
+	blocked   string
+	release   chan struct{}
+	failing   map[string]int
+	processed []string
+	mediated  []string
+}
+
+func (m *blockingMediator) Supports(event, action string) bool {
+	return true
+}
+
+func (m *blockingMediator) Replay(ctx context.Context, envelope *hooks.Envelope) error {
+	if m.blocked != "" && envelope.Repository == m.blocked {
+		<-m.release
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.failing[envelope.DeliveryID] > 0 {
+		m.failing[envelope.DeliveryID]--
+		return errors.New("unavailable")
+	}
+
+	m.processed = append(m.processed, envelope.DeliveryID)
+
+	return nil
+}
+
+func (m *blockingMediator) Mediate(ctx context.Context, event, deliveryID string, payload []byte) error {
+	m.mu.Lock()
+	m.mediated = append(m.mediated, deliveryID)
+	m.mu.Unlock()
+
+	return m.Replay(ctx, &hooks.Envelope{Event: event, DeliveryID: deliveryID, Payload: string(payload)})
+}
+
+func (m *blockingMediator) Processed() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.processed...)
+}
+
+func TestQueueWorker_Run_SlowPullRequest(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	queue := hooks.NewQueueRepository(redisClient)
+
+	var others []string
+
+	// the first delivery blocks, deliveries of other repositories follow it
+	for i, repo := range []string{"octocat/slow", "octocat/hello-world", "octocat/spoon-knife", "blamewarrior/hooks", "blamewarrior/web"} {
+		deliveryID := fmt.Sprintf("%d", i)
+
+		require.NoError(t, queue.Push(&hooks.Envelope{
+			Event:      "member",
+			DeliveryID: deliveryID,
+			Repository: repo,
+			Payload:    fmt.Sprintf(`{"action":"added","repository":{"full_name":%q}}`, repo),
+		}))
+
+		if i > 0 {
+			others = append(others, deliveryID)
+		}
+	}
+
+	mediator := &blockingMediator{blocked: "octocat/slow", release: make(chan struct{})}
+
+	worker := hooks.NewQueueWorker(queue, mediator, 2)
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- worker.Run(context.Background())
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(mediator.Processed()) < len(others) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, others, mediator.Processed(), "deliveries of other repositories waited for the slow one")
+
+	close(mediator.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, worker.Shutdown(ctx))
+	require.NoError(t, <-stopped)
+
+	assert.Equal(t, append(others, "0"), mediator.Processed())
+}
+
+func TestQueueWorker_Run_RetriedInOrder(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	queue := hooks.NewQueueRepository(redisClient)
+
+	for i, action := range []string{"opened", "synchronize", "closed"} {
+		e := queuedEvent{Event: "pull_request", Action: action, Repository: "octocat/hello-world", Number: 1}
+
+		require.NoError(t, queue.Push(&hooks.Envelope{
+			Event:      e.Event,
+			DeliveryID: fmt.Sprintf("%d-%s", i, action),
+			Repository: e.Repository,
+			Payload:    e.payload(),
+		}))
+	}
+
+	mediator := &blockingMediator{
+		// opened fails twice, it is not saved for retry on the last attempt
+		failing: map[string]int{"0-opened": 2},
+	}
+
+	worker := hooks.NewQueueWorker(queue, mediator, 4)
+	worker.RetryBackoff = 10 * time.Millisecond
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- worker.Run(context.Background())
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(mediator.Processed()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, worker.Shutdown(ctx))
+	require.NoError(t, <-stopped)
+
+	assert.Equal(t, []string{"0-opened", "1-synchronize", "2-closed"}, mediator.Processed())
+	assert.Equal(t, []string{"0-opened"}, mediator.mediated)
+}
@@ -0,0 +1,153 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/blamewarrior/hooks/logging"
+)
+
+// Replayer handles the payload of previously failed delivery.
+type Replayer interface {
+	Replay(ctx context.Context, envelope *Envelope) error
+}
+
+// RetryWorker periodically replays saved payloads, backing off exponentially
+// between attempts. Payloads that keep failing are buried after MaxAttempts,
+// the ones that cannot succeed on retry are buried right away.
+type RetryWorker struct {
+	Interval    time.Duration
+	BatchSize   int64
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+
+	payloads Payloads
+	replayer Replayer
+}
+
+func NewRetryWorker(payloads Payloads, replayer Replayer) *RetryWorker {
+	return &RetryWorker{
+		Interval:    time.Minute,
+		BatchSize:   100,
+		MaxAttempts: 10,
+		Backoff:     time.Minute,
+		MaxBackoff:  6 * time.Hour,
+
+		payloads: payloads,
+		replayer: replayer,
+	}
+}
+
+// Run drains saved payloads every Interval until ctx is done.
+func (worker *RetryWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := worker.Drain(ctx); err != nil {
+				logging.FromContext(ctx).WithError(err).Errorf("failed to drain saved payloads")
+			}
+		}
+	}
+}
+
+// Drain replays saved payloads that are due for another attempt. Replayed payloads
+// are deleted, failed ones are either saved back with increased attempts counter
+// or buried if they ran out of attempts. Every call goes through the next BatchSize
+// payloads starting from the earliest saved ones. Draining stops once ctx is done.
+func (worker *RetryWorker) Drain(ctx context.Context) error {
+	envelopes, err := worker.payloads.Rotate(worker.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, envelope := range envelopes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if now.Before(envelope.LastAttemptAt.Add(worker.backoff(envelope.Attempts))) {
+			continue
+		}
+
+		replayErr := worker.replayer.Replay(ctx, envelope)
+
+		if replayErr == nil {
+			if err = worker.payloads.Delete(envelope); err != nil {
+				return err
+			}
+			continue
+		}
+
+		failed := *envelope
+		failed.raw = ""
+		failed.Attempts++
+		failed.LastAttemptAt = now
+		failed.LastError = replayErr.Error()
+
+		logger := logging.FromContext(ctx).WithFields(logging.Fields{
+			"event":       envelope.Event,
+			"delivery_id": envelope.DeliveryID,
+			"repository":  envelope.Repository,
+			"attempts":    failed.Attempts,
+		}).WithError(replayErr)
+
+		if failed.Attempts >= worker.MaxAttempts || isPermanent(replayErr) {
+			logger.Errorf("buried %s event after failed retry", envelope.Event)
+			err = worker.payloads.Bury(&failed)
+		} else {
+			logger.Warnf("failed to retry %s event", envelope.Event)
+			err = worker.payloads.Save(&failed)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = worker.payloads.Delete(envelope); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (worker *RetryWorker) backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	delay := worker.Backoff
+
+	for i := 1; i < attempts && delay < worker.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > worker.MaxBackoff {
+		return worker.MaxBackoff
+	}
+
+	return delay
+}
@@ -0,0 +1,264 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/blamewarrior/hooks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type ReplayerMock struct {
+	mock.Mock
+}
+
+func (m *ReplayerMock) Replay(ctx context.Context, envelope *hooks.Envelope) error {
+	args := m.Called(envelope.Event, envelope.Payload)
+	return args.Error(0)
+}
+
+func TestRetryWorker_Drain_ReplayedPayload(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	payloadRepo := hooks.NewPayloadRepository(redisClient)
+
+	err := payloadRepo.Save(&hooks.Envelope{
+		Event:         "member",
+		Payload:       `{"action":"added"}`,
+		Attempts:      1,
+		LastAttemptAt: time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	replayer := new(ReplayerMock)
+	replayer.On("Replay", "member", `{"action":"added"}`).Return(nil)
+
+	worker := hooks.NewRetryWorker(payloadRepo, replayer)
+
+	err = worker.Drain(context.Background())
+	require.NoError(t, err)
+
+	replayer.AssertExpectations(t)
+
+	list, err := payloadRepo.List(10)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestRetryWorker_Drain_FailedPayload(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	payloadRepo := hooks.NewPayloadRepository(redisClient)
+
+	err := payloadRepo.Save(&hooks.Envelope{
+		Event:         "member",
+		Payload:       `{"action":"added"}`,
+		Attempts:      1,
+		LastAttemptAt: time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	replayer := new(ReplayerMock)
+	replayer.On("Replay", "member", `{"action":"added"}`).Return(fmt.Errorf("unavailable"))
+
+	worker := hooks.NewRetryWorker(payloadRepo, replayer)
+
+	err = worker.Drain(context.Background())
+	require.NoError(t, err)
+
+	list, err := payloadRepo.List(10)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	assert.Equal(t, 2, list[0].Attempts)
+	assert.Equal(t, "unavailable", list[0].LastError)
+	assert.WithinDuration(t, time.Now(), list[0].LastAttemptAt, time.Minute)
+
+	// the next attempt is postponed for 2 minutes
+	err = worker.Drain(context.Background())
+	require.NoError(t, err)
+
+	replayer.AssertNumberOfCalls(t, "Replay", 1)
+}
+
+func TestRetryWorker_Drain_Backlog(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	payloadRepo := hooks.NewPayloadRepository(redisClient)
+
+	replayer := new(ReplayerMock)
+
+	for i := 0; i < 5; i++ {
+		payload := fmt.Sprintf(`{"action":"added","n":%d}`, i)
+
+		err := payloadRepo.Save(&hooks.Envelope{
+			Event:         "member",
+			Payload:       payload,
+			Attempts:      1,
+			LastAttemptAt: time.Now().Add(-time.Hour),
+		})
+		require.NoError(t, err)
+
+		replayer.On("Replay", "member", payload).Return(fmt.Errorf("unavailable")).Once()
+	}
+
+	worker := hooks.NewRetryWorker(payloadRepo, replayer)
+	worker.BatchSize = 3
+
+	// payloads failed again are saved back, yet the ones that have not been
+	// attempted yet are not starved by them
+	require.NoError(t, worker.Drain(context.Background()))
+	replayer.AssertNumberOfCalls(t, "Replay", 3)
+
+	require.NoError(t, worker.Drain(context.Background()))
+	replayer.AssertExpectations(t)
+
+	list, err := payloadRepo.List(10)
+	require.NoError(t, err)
+	assert.Len(t, list, 5)
+}
+
+func TestRetryWorker_Drain_ExhaustedPayload(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	payloadRepo := hooks.NewPayloadRepository(redisClient)
+
+	err := payloadRepo.Save(&hooks.Envelope{
+		Event:         "member",
+		Payload:       `{"action":"added"}`,
+		Attempts:      2,
+		LastAttemptAt: time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	replayer := new(ReplayerMock)
+	replayer.On("Replay", "member", `{"action":"added"}`).Return(fmt.Errorf("unavailable"))
+
+	worker := hooks.NewRetryWorker(payloadRepo, replayer)
+	worker.MaxAttempts = 3
+
+	err = worker.Drain(context.Background())
+	require.NoError(t, err)
+
+	list, err := payloadRepo.List(10)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	dead, err := redisClient.LRange("dead_hooks", 0, 10).Result()
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+
+	buried := new(hooks.Envelope)
+	require.NoError(t, json.Unmarshal([]byte(dead[0]), buried))
+
+	assert.Equal(t, 3, buried.Attempts)
+	assert.Equal(t, "unavailable", buried.LastError)
+}
+
+func TestRetryWorker_Drain_NoEligibleReviewer(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	payloadRepo := hooks.NewPayloadRepository(redisClient)
+
+	err := payloadRepo.Save(&hooks.Envelope{
+		Event:         "pull_request",
+		Payload:       `{"action":"opened"}`,
+		Attempts:      1,
+		LastAttemptAt: time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	replayer := new(ReplayerMock)
+	replayer.On("Replay", "pull_request", `{"action":"opened"}`).Return(hooks.ErrNoEligibleReviewer)
+
+	worker := hooks.NewRetryWorker(payloadRepo, replayer)
+
+	err = worker.Drain(context.Background())
+	require.NoError(t, err)
+
+	list, err := payloadRepo.List(10)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	dead, err := redisClient.LRange("dead_hooks", 0, 10).Result()
+	require.NoError(t, err)
+	assert.Len(t, dead, 1)
+}
+
+func TestRetryWorker_Drain_Backoff(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	payloadRepo := hooks.NewPayloadRepository(redisClient)
+
+	results := []struct {
+		Attempts      int
+		LastAttemptAt time.Time
+		Replayed      bool
+	}{
+		{Attempts: 1, LastAttemptAt: time.Now().Add(-30 * time.Second), Replayed: false},
+		{Attempts: 1, LastAttemptAt: time.Now().Add(-90 * time.Second), Replayed: true},
+		{Attempts: 3, LastAttemptAt: time.Now().Add(-3 * time.Minute), Replayed: false},
+		{Attempts: 3, LastAttemptAt: time.Now().Add(-5 * time.Minute), Replayed: true},
+		{Attempts: 9, LastAttemptAt: time.Now().Add(-4 * time.Hour), Replayed: false},
+		{Attempts: 9, LastAttemptAt: time.Now().Add(-5 * time.Hour), Replayed: true},
+	}
+
+	for _, result := range results {
+		redisClient.FlushDB()
+
+		err := payloadRepo.Save(&hooks.Envelope{
+			Event:         "member",
+			Payload:       `{"action":"added"}`,
+			Attempts:      result.Attempts,
+			LastAttemptAt: result.LastAttemptAt,
+		})
+		require.NoError(t, err)
+
+		replayer := new(ReplayerMock)
+		replayer.On("Replay", "member", `{"action":"added"}`).Return(nil)
+
+		worker := hooks.NewRetryWorker(payloadRepo, replayer)
+
+		err = worker.Drain(context.Background())
+		require.NoError(t, err)
+
+		if result.Replayed {
+			replayer.AssertNumberOfCalls(t, "Replay", 1)
+		} else {
+			replayer.AssertNotCalled(t, "Replay", "member", `{"action":"added"}`)
+		}
+	}
+}
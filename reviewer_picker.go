@@ -0,0 +1,199 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	bw "github.com/blamewarrior/hooks/blamewarrior"
+	gh "github.com/blamewarrior/hooks/github"
+)
+
+var ErrNoEligibleReviewer = errors.New("no eligible reviewer")
+
+// ReviewerPicker chooses up to n distinct reviewers for pull request among candidates.
+type ReviewerPicker interface {
+	Pick(ctx context.Context, pullRequest *bw.PullRequest, candidates []gh.Collaborator, n int) ([]gh.Collaborator, error)
+}
+
+// NewReviewerPicker returns a picker for given strategy name. rnd is used by random
+// strategies and must not be shared with other pickers, blame is used by blame strategy
+// and load by least-loaded one.
+func NewReviewerPicker(strategy string, rnd *rand.Rand, blame gh.Blame, load gh.ReviewLoad) (ReviewerPicker, error) {
+	switch strategy {
+	case "random":
+		return NewRandomPicker(rnd), nil
+	case "random-admin":
+		return NewRandomAdminPicker(rnd), nil
+	case "round-robin":
+		return NewRoundRobinPicker(), nil
+	case "least-loaded":
+		return NewLeastLoadedPicker(load, NewRoundRobinPicker()), nil
+	case "blame":
+		return NewBlamePicker(blame, NewRandomPicker(rnd)), nil
+	default:
+		return nil, fmt.Errorf("unsupported reviewer strategy %s", strategy)
+	}
+}
+
+// RandomPicker picks any of candidates at random.
+type RandomPicker struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func NewRandomPicker(rnd *rand.Rand) *RandomPicker {
+	return &RandomPicker{rnd: rnd}
+}
+
+func (picker *RandomPicker) Pick(ctx context.Context, pullRequest *bw.PullRequest, candidates []gh.Collaborator, n int) ([]gh.Collaborator, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNoEligibleReviewer
+	}
+
+	picker.mu.Lock()
+	perm := picker.rnd.Perm(len(candidates))
+	picker.mu.Unlock()
+
+	picked := make([]gh.Collaborator, 0, n)
+
+	for _, i := range perm[:minInt(n, len(perm))] {
+		picked = append(picked, candidates[i])
+	}
+
+	return picked, nil
+}
+
+// RandomAdminPicker picks repository admins at random. If there are not enough
+// admins among candidates, the rest is picked from other candidates.
+type RandomAdminPicker struct {
+	random *RandomPicker
+}
+
+func NewRandomAdminPicker(rnd *rand.Rand) *RandomAdminPicker {
+	return &RandomAdminPicker{NewRandomPicker(rnd)}
+}
+
+func (picker *RandomAdminPicker) Pick(ctx context.Context, pullRequest *bw.PullRequest, candidates []gh.Collaborator, n int) ([]gh.Collaborator, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNoEligibleReviewer
+	}
+
+	admins := make([]gh.Collaborator, 0)
+	others := make([]gh.Collaborator, 0)
+
+	for _, candidate := range candidates {
+		if candidate.Admin {
+			admins = append(admins, candidate)
+		} else {
+			others = append(others, candidate)
+		}
+	}
+
+	picked := make([]gh.Collaborator, 0, n)
+
+	for _, group := range [][]gh.Collaborator{admins, others} {
+		if len(group) == 0 || len(picked) == n {
+			continue
+		}
+
+		groupPicked, err := picker.random.Pick(ctx, pullRequest, group, n-len(picked))
+		if err != nil {
+			return nil, err
+		}
+
+		picked = append(picked, groupPicked...)
+	}
+
+	return picked, nil
+}
+
+// RoundRobinPicker picks candidates of each repository in turn.
+type RoundRobinPicker struct {
+	mu   sync.Mutex
+	next map[string]int
+}
+
+func NewRoundRobinPicker() *RoundRobinPicker {
+	return &RoundRobinPicker{next: make(map[string]int)}
+}
+
+func (picker *RoundRobinPicker) Pick(ctx context.Context, pullRequest *bw.PullRequest, candidates []gh.Collaborator, n int) ([]gh.Collaborator, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNoEligibleReviewer
+	}
+
+	sorted := sortedByLogin(candidates)
+
+	picker.mu.Lock()
+	defer picker.mu.Unlock()
+
+	next := picker.next[pullRequest.RepositoryName]
+
+	picked := make([]gh.Collaborator, 0, n)
+
+	for len(picked) < minInt(n, len(sorted)) {
+		picked = append(picked, sorted[next%len(sorted)])
+		next++
+	}
+
+	picker.next[pullRequest.RepositoryName] = next % len(sorted)
+
+	return picked, nil
+}
+
+// RepositoryPicker delegates picking to the picker configured for repository,
+// falling back to the default one.
+type RepositoryPicker struct {
+	defaultPicker ReviewerPicker
+	pickers       map[string]ReviewerPicker
+}
+
+func NewRepositoryPicker(defaultPicker ReviewerPicker, pickers map[string]ReviewerPicker) *RepositoryPicker {
+	return &RepositoryPicker{defaultPicker, pickers}
+}
+
+func (picker *RepositoryPicker) Pick(ctx context.Context, pullRequest *bw.PullRequest, candidates []gh.Collaborator, n int) ([]gh.Collaborator, error) {
+	if repoPicker, ok := picker.pickers[pullRequest.RepositoryName]; ok {
+		return repoPicker.Pick(ctx, pullRequest, candidates, n)
+	}
+
+	return picker.defaultPicker.Pick(ctx, pullRequest, candidates, n)
+}
+
+func sortedByLogin(collaborators []gh.Collaborator) []gh.Collaborator {
+	sorted := make([]gh.Collaborator, len(collaborators))
+	copy(sorted, collaborators)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Login < sorted[j].Login
+	})
+
+	return sorted
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,175 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/blamewarrior/hooks"
+	bw "github.com/blamewarrior/hooks/blamewarrior"
+	gh "github.com/blamewarrior/hooks/github"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCandidates = []gh.Collaborator{
+	{Id: 3, Login: "carol", Admin: false},
+	{Id: 1, Login: "alice", Admin: true},
+	{Id: 2, Login: "bob", Admin: false},
+	{Id: 4, Login: "dave", Admin: true},
+}
+
+func TestRandomPicker_Pick(t *testing.T) {
+	picker := hooks.NewRandomPicker(rand.New(rand.NewSource(1)))
+
+	pullRequest := &bw.PullRequest{RepositoryName: "blamewarrior/hooks"}
+
+	picked := make(map[string]int)
+
+	for i := 0; i < 100; i++ {
+		reviewers, err := picker.Pick(context.Background(), pullRequest, testCandidates, 1)
+		require.NoError(t, err)
+		require.Len(t, reviewers, 1)
+
+		picked[reviewers[0].Login]++
+	}
+
+	assert.Len(t, picked, 4)
+
+	reviewers, err := picker.Pick(context.Background(), pullRequest, testCandidates, 3)
+	require.NoError(t, err)
+	assert.Len(t, reviewers, 3)
+	assert.Len(t, logins(reviewers), 3)
+
+	reviewers, err = picker.Pick(context.Background(), pullRequest, testCandidates, 10)
+	require.NoError(t, err)
+	assert.Len(t, logins(reviewers), 4)
+
+	_, err = picker.Pick(context.Background(), pullRequest, nil, 1)
+	assert.Equal(t, hooks.ErrNoEligibleReviewer, err)
+}
+
+func TestRandomAdminPicker_Pick(t *testing.T) {
+	picker := hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1)))
+
+	pullRequest := &bw.PullRequest{RepositoryName: "blamewarrior/hooks"}
+
+	picked := make(map[string]int)
+
+	for i := 0; i < 100; i++ {
+		reviewers, err := picker.Pick(context.Background(), pullRequest, testCandidates, 1)
+		require.NoError(t, err)
+		require.Len(t, reviewers, 1)
+
+		picked[reviewers[0].Login]++
+	}
+
+	assert.Len(t, picked, 2)
+	assert.Contains(t, picked, "alice")
+	assert.Contains(t, picked, "dave")
+
+	reviewers, err := picker.Pick(context.Background(), pullRequest, testCandidates, 3)
+	require.NoError(t, err)
+	require.Len(t, reviewers, 3)
+	assert.True(t, reviewers[0].Admin)
+	assert.True(t, reviewers[1].Admin)
+	assert.False(t, reviewers[2].Admin)
+
+	reviewers, err = picker.Pick(context.Background(), pullRequest, testCandidates[2:3], 1)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", reviewers[0].Login)
+
+	_, err = picker.Pick(context.Background(), pullRequest, nil, 1)
+	assert.Equal(t, hooks.ErrNoEligibleReviewer, err)
+}
+
+func TestRoundRobinPicker_Pick(t *testing.T) {
+	picker := hooks.NewRoundRobinPicker()
+
+	hooksPullRequest := &bw.PullRequest{RepositoryName: "blamewarrior/hooks"}
+	usersPullRequest := &bw.PullRequest{RepositoryName: "blamewarrior/users"}
+
+	results := []struct {
+		PullRequest *bw.PullRequest
+		N           int
+		Logins      []string
+	}{
+		{hooksPullRequest, 1, []string{"alice"}},
+		{hooksPullRequest, 1, []string{"bob"}},
+		{usersPullRequest, 1, []string{"alice"}},
+		{hooksPullRequest, 3, []string{"carol", "dave", "alice"}},
+		{usersPullRequest, 2, []string{"bob", "carol"}},
+		{hooksPullRequest, 5, []string{"bob", "carol", "dave", "alice"}},
+		{hooksPullRequest, 1, []string{"bob"}},
+	}
+
+	for _, result := range results {
+		reviewers, err := picker.Pick(context.Background(), result.PullRequest, testCandidates, result.N)
+		require.NoError(t, err)
+
+		assert.Equal(t, result.Logins, logins(reviewers))
+	}
+
+	_, err := picker.Pick(context.Background(), hooksPullRequest, nil, 1)
+	assert.Equal(t, hooks.ErrNoEligibleReviewer, err)
+}
+
+func TestRepositoryPicker_Pick(t *testing.T) {
+	picker := hooks.NewRepositoryPicker(
+		hooks.NewRoundRobinPicker(),
+		map[string]hooks.ReviewerPicker{
+			"blamewarrior/users": hooks.NewRandomAdminPicker(rand.New(rand.NewSource(1))),
+		},
+	)
+
+	reviewers, err := picker.Pick(context.Background(), &bw.PullRequest{RepositoryName: "blamewarrior/hooks"}, testCandidates, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, logins(reviewers))
+
+	for i := 0; i < 10; i++ {
+		reviewers, err = picker.Pick(context.Background(), &bw.PullRequest{RepositoryName: "blamewarrior/users"}, testCandidates, 1)
+		require.NoError(t, err)
+		assert.True(t, reviewers[0].Admin)
+	}
+}
+
+func TestNewReviewerPicker(t *testing.T) {
+	for _, strategy := range []string{"random", "random-admin", "round-robin", "least-loaded", "blame"} {
+		picker, err := hooks.NewReviewerPicker(strategy, rand.New(rand.NewSource(1)), new(BlameMock), new(ReviewLoadMock))
+		require.NoError(t, err)
+		assert.NotNil(t, picker)
+	}
+
+	_, err := hooks.NewReviewerPicker("most-loaded", rand.New(rand.NewSource(1)), new(BlameMock), new(ReviewLoadMock))
+	assert.EqualError(t, err, "unsupported reviewer strategy most-loaded")
+}
+
+func logins(collaborators []gh.Collaborator) []string {
+	result := make([]string, 0, len(collaborators))
+	seen := make(map[string]bool)
+
+	for _, collaborator := range collaborators {
+		if !seen[collaborator.Login] {
+			result = append(result, collaborator.Login)
+			seen[collaborator.Login] = true
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,68 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+
+	"github.com/go-redis/redis"
+)
+
+var ErrNoSecret = errors.New("no webhook secret for repository")
+
+type Secrets interface {
+	Save(repoFullName, secret string) error
+	Get(repoFullName string) (string, error)
+	Delete(repoFullName string) error
+}
+
+type SecretsRepository struct {
+	redisClient *redis.Client
+}
+
+func NewSecretsRepository(redisClient *redis.Client) *SecretsRepository {
+	return &SecretsRepository{redisClient}
+}
+
+func (repo *SecretsRepository) Save(repoFullName, secret string) error {
+	return repo.redisClient.HSet("webhook_secrets", repoFullName, secret).Err()
+}
+
+func (repo *SecretsRepository) Get(repoFullName string) (string, error) {
+	secret, err := repo.redisClient.HGet("webhook_secrets", repoFullName).Result()
+	if err == redis.Nil {
+		return "", ErrNoSecret
+	}
+
+	return secret, err
+}
+
+func (repo *SecretsRepository) Delete(repoFullName string) error {
+	return repo.redisClient.HDel("webhook_secrets", repoFullName).Err()
+}
+
+// GenerateSecret returns a random hex-encoded secret to sign webhook deliveries with.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,85 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"testing"
+
+	"github.com/blamewarrior/hooks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveSecret(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	secretsRepo := hooks.NewSecretsRepository(redisClient)
+
+	err := secretsRepo.Save("blamewarrior/hooks", "s3cr3t")
+	require.NoError(t, err)
+
+	val, err := redisClient.HGet("webhook_secrets", "blamewarrior/hooks").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", val)
+}
+
+func TestGetSecret(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	err := redisClient.HSet("webhook_secrets", "blamewarrior/hooks", "s3cr3t").Err()
+	require.NoError(t, err)
+
+	secretsRepo := hooks.NewSecretsRepository(redisClient)
+
+	secret, err := secretsRepo.Get("blamewarrior/hooks")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", secret)
+
+	_, err = secretsRepo.Get("blamewarrior/users")
+	assert.Equal(t, hooks.ErrNoSecret, err)
+}
+
+func TestDeleteSecret(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	err := redisClient.HSet("webhook_secrets", "blamewarrior/hooks", "s3cr3t").Err()
+	require.NoError(t, err)
+
+	secretsRepo := hooks.NewSecretsRepository(redisClient)
+
+	err = secretsRepo.Delete("blamewarrior/hooks")
+	require.NoError(t, err)
+
+	_, err = secretsRepo.Get("blamewarrior/hooks")
+	assert.Equal(t, hooks.ErrNoSecret, err)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := hooks.GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 40)
+
+	another, err := hooks.GenerateSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, another)
+}
@@ -0,0 +1,91 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+var ErrNoTracking = errors.New("repository tracking is not confirmed")
+
+// Tracking records that GitHub has confirmed the hook created for repository
+// by sending a ping event.
+type Tracking struct {
+	Repository  string    `json:"repository"`
+	HookId      int       `json:"hook_id"`
+	ConfirmedAt time.Time `json:"confirmed_at"`
+}
+
+type Trackings interface {
+	Confirm(repoFullName string, hookID int) error
+	Get(repoFullName string) (*Tracking, error)
+	Delete(repoFullName string) error
+}
+
+type TrackingRepository struct {
+	redisClient *redis.Client
+}
+
+func NewTrackingRepository(redisClient *redis.Client) *TrackingRepository {
+	return &TrackingRepository{redisClient}
+}
+
+func (repo *TrackingRepository) Confirm(repoFullName string, hookID int) error {
+	b, err := json.Marshal(&Tracking{
+		Repository:  repoFullName,
+		HookId:      hookID,
+		ConfirmedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return repo.redisClient.HSet("trackings", trackingKey(repoFullName), string(b)).Err()
+}
+
+func (repo *TrackingRepository) Get(repoFullName string) (*Tracking, error) {
+	s, err := repo.redisClient.HGet("trackings", trackingKey(repoFullName)).Result()
+	if err == redis.Nil {
+		return nil, ErrNoTracking
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	tracking := new(Tracking)
+	if err := json.Unmarshal([]byte(s), tracking); err != nil {
+		return nil, err
+	}
+
+	return tracking, nil
+}
+
+func (repo *TrackingRepository) Delete(repoFullName string) error {
+	return repo.redisClient.HDel("trackings", trackingKey(repoFullName)).Err()
+}
+
+// trackingKey returns the key of repository tracking. GitHub repository names are
+// case-insensitive, pings carry their canonical case while tracking requests carry
+// the case they have been made with.
+func trackingKey(repoFullName string) string {
+	return strings.ToLower(repoFullName)
+}
@@ -0,0 +1,85 @@
+/*
+   Copyright (C) 2017 The BlameWarrior Authors.
+   This file is a part of BlameWarrior service.
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package hooks_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blamewarrior/hooks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmTracking(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	trackings := hooks.NewTrackingRepository(redisClient)
+
+	_, err := trackings.Get("blamewarrior/hooks")
+	assert.Equal(t, hooks.ErrNoTracking, err)
+
+	err = trackings.Confirm("blamewarrior/hooks", 12345678)
+	require.NoError(t, err)
+
+	tracking, err := trackings.Get("blamewarrior/hooks")
+	require.NoError(t, err)
+
+	assert.Equal(t, "blamewarrior/hooks", tracking.Repository)
+	assert.Equal(t, 12345678, tracking.HookId)
+	assert.WithinDuration(t, time.Now(), tracking.ConfirmedAt, time.Minute)
+}
+
+func TestDeleteTracking(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	trackings := hooks.NewTrackingRepository(redisClient)
+
+	err := trackings.Confirm("blamewarrior/hooks", 12345678)
+	require.NoError(t, err)
+
+	err = trackings.Delete("blamewarrior/hooks")
+	require.NoError(t, err)
+
+	_, err = trackings.Get("blamewarrior/hooks")
+	assert.Equal(t, hooks.ErrNoTracking, err)
+}
+
+func TestTracking_CaseInsensitive(t *testing.T) {
+	redisClient, teardown := setup()
+
+	defer teardown()
+
+	trackings := hooks.NewTrackingRepository(redisClient)
+
+	err := trackings.Confirm("BlameWarrior/Hooks", 12345678)
+	require.NoError(t, err)
+
+	tracking, err := trackings.Get("blamewarrior/hooks")
+	require.NoError(t, err)
+	assert.Equal(t, 12345678, tracking.HookId)
+
+	err = trackings.Delete("blamewarrior/hooks")
+	require.NoError(t, err)
+
+	_, err = trackings.Get("BlameWarrior/Hooks")
+	assert.Equal(t, hooks.ErrNoTracking, err)
+}